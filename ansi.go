@@ -0,0 +1,126 @@
+package wrap
+
+import "strings"
+
+// sgrReset is the Select Graphic Rendition sequence that clears all styles.
+const sgrReset = "\x1b[0m"
+
+// escapeLength returns the length in bytes of the ANSI escape sequence at the
+// start of s, or 0 if s doesn't start with one. Unterminated sequences extend
+// to the end of s.
+func escapeLength(s string) int {
+	switch {
+	case strings.HasPrefix(s, "\x1b["):
+		return 2 + csiLength(s[2:])
+	case strings.HasPrefix(s, "\u009b"):
+		return len("\u009b") + csiLength(s[len("\u009b"):])
+	case strings.HasPrefix(s, "\u009d"):
+		return len("\u009d") + stringLength(s[len("\u009d"):])
+	case len(s) < 2 || s[0] != '\x1b':
+		return 0
+	}
+
+	switch s[1] {
+	case ']', 'P', 'X', '^', '_':
+		// OSC, DCS, SOS, PM and APC carry a string terminated by ST
+		return 2 + stringLength(s[2:])
+	}
+
+	// Other escape sequences are any number of intermediate bytes followed
+	// by a single final byte.
+	i := 1
+	for i < len(s) && s[i] >= 0x20 && s[i] <= 0x2F {
+		i++
+	}
+	if i < len(s) && s[i] >= 0x30 && s[i] <= 0x7E {
+		return i + 1
+	}
+	return i
+}
+
+// csiLength returns the length of the parameters, intermediates and final
+// byte of a control sequence, with the introducer already removed.
+func csiLength(s string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7E {
+			return i + 1
+		}
+		if s[i] < 0x20 || s[i] > 0x3F {
+			// Not a valid parameter or intermediate byte, so end the sequence
+			// here rather than swallowing the text that follows.
+			return i
+		}
+	}
+	return len(s)
+}
+
+// stringLength returns the length of a control string and its terminator,
+// which is either BEL or ST, with the introducer already removed.
+func stringLength(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\a':
+			return i + 1
+		case strings.HasPrefix(s[i:], "\x1b\\"):
+			return i + 2
+		case strings.HasPrefix(s[i:], "\u009c"):
+			return i + len("\u009c")
+		}
+	}
+	return len(s)
+}
+
+// isSGR reports whether the escape sequence selects graphic rendition, that
+// is, it sets or resets styles such as colors and emphasis.
+func isSGR(seq string) bool {
+	return strings.HasPrefix(seq, "\x1b[") && strings.HasSuffix(seq, "m") &&
+		strings.Trim(seq[2:len(seq)-1], "0123456789;:") == ""
+}
+
+// styleState tracks the SGR escape sequences that style the text written so
+// far, so they can be closed at the end of a line and reapplied on the next.
+type styleState struct {
+	active string
+}
+
+// update records the effect of the SGR escape sequences in s.
+func (st *styleState) update(s string) {
+	for i := strings.IndexByte(s, '\x1b'); i >= 0; i = strings.IndexByte(s, '\x1b') {
+		s = s[i:]
+		n := escapeLength(s)
+		if n == 0 {
+			n = 1
+		}
+		if seq := s[:n]; isSGR(seq) {
+			params := seq[2 : len(seq)-1]
+			if first, _, _ := strings.Cut(params, ";"); first == "" || strings.Trim(first, "0") == "" {
+				// Reset any previous styles
+				st.active = ""
+			}
+			if strings.Trim(params, "0") != "" {
+				st.active += seq
+			}
+		}
+		s = s[n:]
+	}
+}
+
+// nextCluster returns the length in bytes of the grapheme cluster, or the
+// escape sequence if EscapeSequences is enabled, at the start of s.
+func (w *wrapping) nextCluster(s string) int {
+	if w.EscapeSequences {
+		if n := escapeLength(s); n > 0 {
+			return n
+		}
+	}
+	if n := w.protectedLength(s); n > 0 {
+		return n
+	}
+	return nextGrapheme(s)
+}
+
+// isEscape reports whether the cluster returned by nextCluster is an escape
+// sequence.
+func (w *wrapping) isEscape(cluster string) bool {
+	return w.EscapeSequences && escapeLength(cluster) > 0
+}
@@ -0,0 +1,60 @@
+package wrap
+
+import "testing"
+
+func TestEscapeLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int
+	}{
+		{"not an escape", "hello", 0},
+		{"lone escape", "\x1b", 0},
+		{"SGR", "\x1b[31mred", 5},
+		{"SGR reset", "\x1b[mtext", 3},
+		{"CSI with private parameters", "\x1b[?25lhidden", 6},
+		{"C1 CSI", "\u009b1mbold", 4},
+		{"unterminated CSI", "\x1b[31", 4},
+		{"invalid CSI byte", "\x1b[3é", 3},
+		{"OSC terminated by BEL", "\x1b]0;title\atext", 10},
+		{"OSC terminated by ST", "\x1b]8;;http://a-b.c\x1b\\link", 19},
+		{"unterminated OSC", "\x1b]8;;http://", 12},
+		{"two character escape", "\x1b7text", 2},
+		{"escape with intermediate", "\x1b(Btext", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeLength(tt.input); got != tt.expected {
+				t.Errorf("escapeLength(%q) = %d, want %d", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStyleState_Update(t *testing.T) {
+	tests := []struct {
+		name     string
+		initial  string
+		input    string
+		expected string
+	}{
+		{"no escapes", "", "hello", ""},
+		{"single style", "", "\x1b[31mred", "\x1b[31m"},
+		{"accumulated styles", "\x1b[1m", "\x1b[31mred", "\x1b[1m\x1b[31m"},
+		{"reset", "\x1b[1m", "bold\x1b[0m", ""},
+		{"short reset", "\x1b[1m", "bold\x1b[m", ""},
+		{"reset then style", "\x1b[1m", "\x1b[0;32mgreen", "\x1b[0;32m"},
+		{"ignores other sequences", "", "\x1b[2K\x1b]0;title\a", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := styleState{active: tt.initial}
+			st.update(tt.input)
+			if st.active != tt.expected {
+				t.Errorf("got %q, want %q", st.active, tt.expected)
+			}
+		})
+	}
+}
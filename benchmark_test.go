@@ -66,3 +66,12 @@ func BenchmarkGreedyVsOptimal(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkLineCount(b *testing.B) {
+	w := wrap.NewWrapper()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.LineCount(loremIpsums[0], 80)
+	}
+}
@@ -0,0 +1,398 @@
+// Command wrap wraps text read from files or standard input, in the manner of
+// fmt(1) and par.
+//
+// Usage:
+//
+//	wrap [flags] [file ...]
+//
+// Without any files, standard input is wrapped and written to standard
+// output. Every setting of wrap.Wrapper has a flag, apart from Measurer,
+// which measures text in a proportional font. String flags accept Go
+// escape sequences, so a Windows newline is given as -newline '\r\n'.
+//
+// With -i, each file is wrapped in place. With -truncate, each line is
+// shortened to the width instead of being wrapped. With -check, nothing is
+// written other than the location of every line already wider than the limit,
+// and wrap exits with status 1 if there are any.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bbrks/wrap/v2"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// algorithms, alignments and truncateModes map flag values to settings.
+var (
+	algorithms = map[string]wrap.Algorithm{
+		"greedy":             wrap.AlgorithmGreedy,
+		"minimum-raggedness": wrap.AlgorithmMinimumRaggedness,
+		"knuth-plass":        wrap.AlgorithmKnuthPlass,
+		"balanced":           wrap.AlgorithmBalanced,
+	}
+	alignments = map[string]wrap.Alignment{
+		"left":    wrap.AlignLeft,
+		"justify": wrap.AlignJustify,
+		"center":  wrap.AlignCenter,
+		"right":   wrap.AlignRight,
+	}
+	truncateModes = map[string]wrap.TruncateMode{
+		"end":    wrap.TruncateEnd,
+		"start":  wrap.TruncateStart,
+		"middle": wrap.TruncateMiddle,
+	}
+)
+
+// run runs the command with the given arguments and standard streams, and
+// returns its exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	w := wrap.NewWrapper()
+	fs := flag.NewFlagSet("wrap", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: wrap [flags] [file ...]")
+		fs.PrintDefaults()
+	}
+
+	limit := fs.Int("w", 80, "wrap lines at `width` columns, or not at all if less than 1")
+	inPlace := fs.Bool("i", false, "wrap files in place")
+	check := fs.Bool("check", false, "report lines wider than the limit instead of wrapping, exiting with status 1 if there are any")
+	reflow := fs.Bool("reflow", false, "join the lines of each paragraph before wrapping")
+	markdown := fs.Bool("markdown", false, "wrap the input as a Markdown document")
+	truncate := false
+	fs.Func("truncate", "truncate each line to the width instead of wrapping, removing its `end`, start or middle", func(s string) error {
+		m, ok := truncateModes[s]
+		if !ok {
+			return fmt.Errorf("unknown truncate mode %q", s)
+		}
+		w.TruncateMode, truncate = m, true
+		return nil
+	})
+
+	fs.Var(escaped{&w.Breakpoints}, "breakpoints", "`characters` to break lines at")
+	fs.Var(escaped{&w.Newline}, "newline", "`string` that ends each line")
+	fs.Var(escaped{&w.OutputLinePrefix}, "prefix", "`string` to prefix every output line with")
+	fs.Var(escaped{&w.OutputLineSuffix}, "suffix", "`string` to suffix every output line with")
+	fs.Var(escaped{&w.FirstLinePrefix}, "first-prefix", "`string` to prefix the first line of each paragraph with, after -prefix")
+	fs.Var(escaped{&w.ContinuationPrefix}, "continuation-prefix", "`string` to prefix the other lines of each paragraph with, after -prefix")
+	fs.BoolVar(&w.LimitIncludesPrefixSuffix, "limit-includes-prefix", w.LimitIncludesPrefixSuffix, "count prefixes and suffixes towards the limit")
+	fs.Var(escaped{&w.TrimInputPrefix}, "trim-prefix", "`string` to trim from the start of every input line")
+	fs.Var(escaped{&w.TrimInputSuffix}, "trim-suffix", "`string` to trim from the end of every input line")
+	fs.BoolVar(&w.StripTrailingNewline, "strip-trailing-newline", w.StripTrailingNewline, "don't end the output with a newline")
+	fs.BoolVar(&w.CutLongWords, "cut", w.CutLongWords, "cut words wider than the limit")
+	fs.BoolVar(&w.UnicodeLineBreaking, "unicode", w.UnicodeLineBreaking, "break lines using the Unicode line breaking algorithm")
+	fs.BoolVar(&w.MinimumRaggedness, "minimum-raggedness", w.MinimumRaggedness, "use the minimum raggedness algorithm")
+	fs.Func("algorithm", "line breaking `algorithm`: greedy, minimum-raggedness, knuth-plass or balanced", func(s string) error {
+		a, ok := algorithms[s]
+		if !ok {
+			return fmt.Errorf("unknown algorithm %q", s)
+		}
+		w.Algorithm = a
+		return nil
+	})
+	fs.Float64Var(&w.KnuthPlass.Tolerance, "tolerance", w.KnuthPlass.Tolerance, "largest badness allowed for a line by knuth-plass")
+	fs.Float64Var(&w.KnuthPlass.SpaceStretch, "space-stretch", w.KnuthPlass.SpaceStretch, "how far spaces stretch for knuth-plass, as a fraction of their width")
+	fs.Float64Var(&w.KnuthPlass.SpaceShrink, "space-shrink", w.KnuthPlass.SpaceShrink, "how far spaces shrink for knuth-plass, as a fraction of their width")
+	fs.Float64Var(&w.KnuthPlass.LinePenalty, "line-penalty", w.KnuthPlass.LinePenalty, "badness added to every line by knuth-plass")
+	fs.Float64Var(&w.KnuthPlass.HyphenPenalty, "hyphen-penalty", w.KnuthPlass.HyphenPenalty, "penalty for breaking after a hyphen with knuth-plass")
+	fs.Float64Var(&w.KnuthPlass.FlaggedDemerits, "flagged-demerits", w.KnuthPlass.FlaggedDemerits, "demerits for consecutive hyphenated lines with knuth-plass")
+	fs.Float64Var(&w.KnuthPlass.FitnessDemerits, "fitness-demerits", w.KnuthPlass.FitnessDemerits, "demerits for consecutive lines spaced very differently with knuth-plass")
+	fs.IntVar(&w.Widows.MinWidth, "widow-width", w.Widows.MinWidth, "narrowest `width` the last line of a paragraph can be without -widow-penalty")
+	fs.Float64Var(&w.Widows.Penalty, "widow-penalty", w.Widows.Penalty, "penalty for a last line narrower than -widow-width with minimum-raggedness or balanced")
+	fs.Float64Var(&w.Widows.OrphanPenalty, "orphan-penalty", w.Widows.OrphanPenalty, "penalty for a last line holding a single word with minimum-raggedness or balanced")
+	hyphenate := fs.Bool("hyphenate", false, "hyphenate words using American English patterns")
+	patterns := fs.String("hyphenation-patterns", "", "hyphenate words using the TeX hyphenation patterns in `file`")
+	fs.IntVar(&w.Hyphenation.MinLeft, "hyphen-min-left", w.Hyphenation.MinLeft, "fewest `characters` to leave before a hyphenation point")
+	fs.IntVar(&w.Hyphenation.MinRight, "hyphen-min-right", w.Hyphenation.MinRight, "fewest `characters` to leave after a hyphenation point")
+	fs.Var(escaped{&w.Hyphenation.Hyphen}, "hyphen", "`string` to end a hyphenated line with")
+	fs.Float64Var(&w.Hyphenation.Penalty, "hyphenation-penalty", w.Hyphenation.Penalty, "penalty for hyphenating a word with minimum-raggedness or balanced")
+	fs.BoolVar(&w.Protect.URLs, "protect-urls", w.Protect.URLs, "never break lines within URLs")
+	fs.BoolVar(&w.Protect.Emails, "protect-emails", w.Protect.Emails, "never break lines within email addresses")
+	fs.BoolVar(&w.Protect.Paths, "protect-paths", w.Protect.Paths, "never break lines within file paths")
+	fs.BoolVar(&w.Protect.Code, "protect-code", w.Protect.Code, "never break lines within code spans between backticks")
+	fs.Func("protect-pattern", "never break lines within text matching the `regexp`, which may be repeated", func(s string) error {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return err
+		}
+		// Repeated patterns are protected as alternatives of one
+		if w.Protect.Pattern != nil {
+			re = regexp.MustCompile(`(?:` + w.Protect.Pattern.String() + `)|(?:` + s + `)`)
+		}
+		w.Protect.Pattern = re
+		return nil
+	})
+	fs.IntVar(&w.Tabs.Width, "tab-width", w.Tabs.Width, "expand tabs to tab stops every `columns`")
+	fs.Func("tab-stops", "expand tabs to tab stops at a comma-separated list of `columns`, then every -tab-width", func(s string) error {
+		var stops []int
+		for _, f := range strings.Split(s, ",") {
+			stop, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil || stop < 0 {
+				return fmt.Errorf("invalid tab stop %q", f)
+			}
+			stops = append(stops, stop)
+		}
+		w.Tabs.Stops = wrap.NewTabStops(stops...)
+		return nil
+	})
+	fs.BoolVar(&w.Tabs.Keep, "keep-tabs", w.Tabs.Keep, "write tabs rather than the spaces they expand to")
+	fs.BoolVar(&w.PreserveIndent, "preserve-indent", w.PreserveIndent, "keep the indent and list markers of each input line")
+	fs.Func("align", "line `alignment`: left, justify, center or right", func(s string) error {
+		a, ok := alignments[s]
+		if !ok {
+			return fmt.Errorf("unknown alignment %q", s)
+		}
+		w.Alignment = a
+		return nil
+	})
+	fs.Func("fill", "`character` to pad aligned lines with (default ' ')", func(s string) error {
+		s, err := unescape(s)
+		if err != nil {
+			return err
+		}
+		r, n := utf8.DecodeRuneInString(s)
+		if n == 0 || n != len(s) {
+			return errors.New("must be a single character")
+		}
+		w.Fill = r
+		return nil
+	})
+	fs.BoolVar(&w.JustifyLastLine, "justify-last-line", w.JustifyLastLine, "justify the last line of each paragraph too")
+	fs.BoolVar(&w.DisplayWidth, "display-width", w.DisplayWidth, "measure text by its display width in a terminal")
+	fs.IntVar(&w.AmbiguousWidth, "ambiguous-width", w.AmbiguousWidth, "`width` of East Asian ambiguous characters with -display-width")
+	fs.BoolVar(&w.EscapeSequences, "escapes", w.EscapeSequences, "treat ANSI escape sequences as zero width")
+	fs.BoolVar(&w.ReapplyStyles, "reapply-styles", w.ReapplyStyles, "close and reapply ANSI styles around every line break")
+	fs.IntVar(&w.MaxLines, "max-lines", w.MaxLines, "write at most `n` lines, ending the last with -ellipsis if text is dropped")
+	fs.Var(escaped{&w.Ellipsis}, "ellipsis", "`string` to end the last line with when -max-lines drops text, or to replace text removed by -truncate")
+	fs.BoolVar(&w.TruncateAtBreakpoints, "truncate-at-breakpoints", w.TruncateAtBreakpoints, "remove whole words with -truncate where possible")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *hyphenate {
+		w.Hyphenation.Hyphenator = wrap.HyphenatorEnUS()
+	}
+	if *patterns != "" {
+		h, err := readHyphenator(*patterns)
+		if err != nil {
+			fmt.Fprintln(stderr, "wrap:", err)
+			return 2
+		}
+		w.Hyphenation.Hyphenator = h
+	}
+	files := fs.Args()
+	if *inPlace && len(files) == 0 {
+		fmt.Fprintln(stderr, "wrap: -i requires file arguments")
+		return 2
+	}
+
+	format := w.Wrap
+	switch {
+	case *markdown:
+		format = w.WrapMarkdown
+	case *reflow:
+		format = w.Reflow
+	case truncate:
+		format = func(s string, limit int) string {
+			return truncateLines(w, s, limit)
+		}
+	}
+
+	status := 0
+	process := func(name string, input []byte) {
+		if *check {
+			for _, n := range overlong(w, string(input), *limit) {
+				fmt.Fprintf(stdout, "%s:%d: line exceeds %d columns\n", name, n, *limit)
+				status = 1
+			}
+			return
+		}
+		// The final line ending is written by the Wrapper, so empty input
+		// gives a single line ending, as the Wrapper gives for ""
+		output := format(strings.TrimSuffix(string(input), w.Newline), *limit)
+		if !*inPlace {
+			io.WriteString(stdout, output)
+			return
+		}
+		if err := replaceFile(name, []byte(output)); err != nil {
+			fmt.Fprintln(stderr, "wrap:", err)
+			status = 1
+		}
+	}
+
+	if len(files) == 0 {
+		input, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, "wrap:", err)
+			return 1
+		}
+		process("<stdin>", input)
+		return status
+	}
+	for _, name := range files {
+		input, err := os.ReadFile(name)
+		if err != nil {
+			fmt.Fprintln(stderr, "wrap:", err)
+			status = 1
+			continue
+		}
+		process(name, input)
+	}
+	return status
+}
+
+// replaceFile replaces the contents of the named file with data, keeping its
+// permissions. The data is written to a temporary file in the same directory,
+// which is renamed over the original, so that the file is never left partly
+// written. A symbolic link is followed, so that the file it names is replaced
+// rather than the link.
+func replaceFile(name string, data []byte) error {
+	if target, err := filepath.EvalSymlinks(name); err == nil {
+		name = target
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(info.Mode().Perm())
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// readHyphenator reads the hyphenation patterns in the named file.
+func readHyphenator(name string) (*wrap.Hyphenator, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h, err := wrap.NewHyphenator(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, strings.TrimPrefix(err.Error(), "wrap: "))
+	}
+	return h, nil
+}
+
+// overlong returns the line numbers of the lines of s that are wider than
+// limit, as measured by w.
+func overlong(w wrap.Wrapper, s string, limit int) []int {
+	if limit < 1 {
+		return nil
+	}
+
+	// Measure each line as it is, without any of the other settings
+	mw := wrap.NewWrapper()
+	mw.DisplayWidth = w.DisplayWidth
+	mw.AmbiguousWidth = w.AmbiguousWidth
+	mw.EscapeSequences = w.EscapeSequences
+	mw.Measurer = w.Measurer
+	mw.Tabs = w.Tabs
+
+	var lines []int
+	for i, line := range strings.Split(strings.TrimSuffix(s, w.Newline), w.Newline) {
+		if mw.MaxWidth(line, 0) > limit {
+			lines = append(lines, i+1)
+		}
+	}
+	return lines
+}
+
+// truncateLines truncates each line of s to limit, ending the result with a
+// newline unless w.StripTrailingNewline is set.
+func truncateLines(w wrap.Wrapper, s string, limit int) string {
+	if w.Newline == "" {
+		w.Newline = "\n"
+	}
+	lines := strings.Split(s, w.Newline)
+	for i, line := range lines {
+		lines[i] = w.Truncate(line, limit)
+	}
+	out := strings.Join(lines, w.Newline)
+	if !w.StripTrailingNewline {
+		out += w.Newline
+	}
+	return out
+}
+
+// escaped is a flag.Value for a string that may contain Go escape sequences.
+type escaped struct {
+	s *string
+}
+
+func (e escaped) String() string {
+	if e.s == nil {
+		return strconv.Quote("")
+	}
+	return strconv.Quote(*e.s)
+}
+
+func (e escaped) Set(s string) (err error) {
+	*e.s, err = unescape(s)
+	return err
+}
+
+// unescape interprets the Go escape sequences in s, such as \t, \r\n, \\ or
+// \u00AD. Quotes may be escaped, but needn't be.
+func unescape(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var sb strings.Builder
+	rest := s
+	for {
+		i := strings.IndexByte(rest, '\\')
+		if i < 0 {
+			sb.WriteString(rest)
+			return sb.String(), nil
+		}
+		sb.WriteString(rest[:i])
+		rest = rest[i:]
+		if len(rest) > 1 && (rest[1] == '"' || rest[1] == '\'') {
+			sb.WriteByte(rest[1])
+			rest = rest[2:]
+			continue
+		}
+		r, multibyte, tail, err := strconv.UnquoteChar(rest, 0)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape sequence in %q", s)
+		}
+		if multibyte {
+			sb.WriteRune(r)
+		} else {
+			// \x and octal escapes give a single byte
+			sb.WriteByte(byte(r))
+		}
+		rest = tail
+	}
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	const fox = "The quick brown fox jumps over the lazy dog.\n"
+
+	tests := []struct {
+		name   string
+		args   []string
+		input  string
+		output string
+		errors string
+		status int
+	}{
+		{
+			name:   "default",
+			args:   []string{"-w", "20"},
+			input:  fox,
+			output: "The quick brown fox\njumps over the lazy\ndog.\n",
+		},
+		{
+			name:   "prefix",
+			args:   []string{"-w", "20", "-prefix", "// ", "-limit-includes-prefix"},
+			input:  fox,
+			output: "// The quick brown\n// fox jumps over\n// the lazy dog.\n",
+		},
+		{
+			name:   "escaped strings",
+			args:   []string{"-w", "20", "-newline", `\r\n`, "-trim-prefix", `\t`},
+			input:  "\tThe quick brown fox jumps\r\n",
+			output: "The quick brown fox\r\njumps\r\n",
+		},
+		{
+			name:   "escaped quotes",
+			args:   []string{"-w", "20", "-prefix", `\"\\ `, "-suffix", ` "\u00bb`},
+			input:  "one two three\n",
+			output: "\"\\ one two three \"»\n",
+		},
+		{
+			name:   "algorithm and alignment",
+			args:   []string{"-w", "20", "-algorithm", "minimum-raggedness", "-align", "right", "-fill", "."},
+			input:  fox,
+			output: ".....The quick brown\n......fox jumps over\n.......the lazy dog.\n",
+		},
+		{
+			name:   "orphan penalty",
+			args:   []string{"-w", "13", "-algorithm", "minimum-raggedness", "-orphan-penalty", "100"},
+			input:  "To be, or not to be, that is the question.\n",
+			output: "To be, or\nnot to be,\nthat is\nthe question.\n",
+		},
+		{
+			name:   "hyphenate",
+			args:   []string{"-w", "14", "-hyphenate", "-hyphen-min-left", "5"},
+			input:  "The international hyphenation algorithm breaks paragraphs nicely.\n",
+			output: "The interna-\ntional hyphen-\nation\nalgorithm\nbreaks\nparagraphs\nnicely.\n",
+		},
+		{
+			name:   "protect",
+			args:   []string{"-w", "16", "-protect-urls", "-protect-code", "-protect-pattern", `v\d+-\d+`},
+			input:  "Read https://example.com/read-me, run `make check-all` on v1-2.\n",
+			output: "Read\nhttps://example.com/read-me,\nrun\n`make check-all`\non v1-2.\n",
+		},
+		{
+			name:   "repeated protect patterns",
+			args:   []string{"-w", "6", "-protect-pattern", `a-b`, "-protect-pattern", `c-d`},
+			input:  "x a-b c-d e-f\n",
+			output: "x a-b\nc-d e-\nf\n",
+		},
+		{
+			name:   "tabs",
+			args:   []string{"-w", "16", "-tab-stops", "4,10", "-tab-width", "4", "-prefix", "#"},
+			input:  "a\tb\tc\td e\tf\n",
+			output: "#a  b     c d\n#e  f\n",
+		},
+		{
+			name:   "kept tabs",
+			args:   []string{"-w", "12", "-tab-width", "8", "-keep-tabs"},
+			input:  "key\tvalue and more\n",
+			output: "key\tvalue\nand more\n",
+		},
+		{
+			name:   "reflow",
+			args:   []string{"-w", "30", "-reflow"},
+			input:  "The quick\nbrown fox.\n\nSecond\nparagraph.\n",
+			output: "The quick brown fox.\n\nSecond paragraph.\n",
+		},
+		{
+			name:   "markdown",
+			args:   []string{"-w", "20", "-markdown"},
+			input:  "# The quick brown fox\n\nThe quick brown fox jumps\n\n    code that stays put\n",
+			output: "# The quick brown fox\n\nThe quick brown fox\njumps\n\n    code that stays put\n",
+		},
+		{
+			name:   "max lines",
+			args:   []string{"-w", "20", "-max-lines", "2", "-ellipsis", " [...]"},
+			input:  fox,
+			output: "The quick brown fox\njumps over the [...]\n",
+		},
+		{
+			name:   "truncate",
+			args:   []string{"-w", "12", "-truncate", "middle", "-truncate-at-breakpoints"},
+			input:  fox + "short\n",
+			output: "The…dog.\nshort\n",
+		},
+		{
+			name:   "unknown truncate mode",
+			args:   []string{"-truncate", "both"},
+			errors: `invalid value "both" for flag -truncate: unknown truncate mode "both"`,
+			status: 2,
+		},
+		{
+			name:   "empty",
+			args:   []string{"-w", "20"},
+			input:  "",
+			output: "\n",
+		},
+		{
+			name:   "check",
+			args:   []string{"-w", "20", "-check"},
+			input:  "short\n" + fox + "exactly twenty chars\n",
+			output: "<stdin>:2: line exceeds 20 columns\n",
+			status: 1,
+		},
+		{
+			name:   "check passes",
+			args:   []string{"-w", "20", "-check", "-display-width"},
+			input:  "short\n日本語のテキスト\n",
+			output: "",
+		},
+		{
+			name:   "check tabs",
+			args:   []string{"-w", "6", "-tab-width", "8", "-check"},
+			input:  "abc\n\t\tabc\n",
+			output: "<stdin>:2: line exceeds 6 columns\n",
+			status: 1,
+		},
+		{
+			name:   "unknown algorithm",
+			args:   []string{"-algorithm", "fast"},
+			errors: `invalid value "fast" for flag -algorithm: unknown algorithm "fast"`,
+			status: 2,
+		},
+		{
+			name:   "invalid protect pattern",
+			args:   []string{"-protect-pattern", "("},
+			errors: `invalid value "(" for flag -protect-pattern: error parsing regexp: missing closing ): ` + "`(`",
+			status: 2,
+		},
+		{
+			name:   "invalid tab stop",
+			args:   []string{"-tab-stops", "4,x"},
+			errors: `invalid value "4,x" for flag -tab-stops: invalid tab stop "x"`,
+			status: 2,
+		},
+		{
+			name:   "fill too long",
+			args:   []string{"-fill", "ab"},
+			errors: `invalid value "ab" for flag -fill: must be a single character`,
+			status: 2,
+		},
+		{
+			name:   "empty without trailing newline",
+			args:   []string{"-w", "20", "-strip-trailing-newline"},
+			output: "",
+		},
+		{
+			name:   "empty markdown",
+			args:   []string{"-w", "20", "-markdown"},
+			output: "\n",
+		},
+		{
+			name:   "empty reflow",
+			args:   []string{"-w", "20", "-reflow"},
+			output: "\n",
+		},
+		{
+			name:   "empty truncate",
+			args:   []string{"-w", "20", "-truncate", "end"},
+			output: "\n",
+		},
+		{
+			name:   "invalid escape",
+			args:   []string{"-prefix", `\q`},
+			errors: `invalid value "\\q" for flag -prefix: invalid escape sequence in "\\q"`,
+			status: 2,
+		},
+		{
+			name:   "in place without files",
+			args:   []string{"-i"},
+			errors: "wrap: -i requires file arguments",
+			status: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			status := run(tt.args, strings.NewReader(tt.input), &stdout, &stderr)
+			if status != tt.status {
+				t.Errorf("got status %d, want %d: %s", status, tt.status, stderr.String())
+			}
+			if got := stdout.String(); got != tt.output {
+				t.Errorf("got %q, want %q", got, tt.output)
+			}
+			if !strings.Contains(stderr.String(), tt.errors) {
+				t.Errorf("got errors %q, want them to contain %q", stderr.String(), tt.errors)
+			}
+		})
+	}
+}
+
+func TestRun_HyphenationPatterns(t *testing.T) {
+	dir := t.TempDir()
+	patterns, invalid := filepath.Join(dir, "hyph.tex"), filepath.Join(dir, "invalid.tex")
+	if err := os.WriteFile(patterns, []byte("\\patterns{ 1ba }\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(invalid, []byte("\\input hyph\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"-w", "6", "-hyphenation-patterns", patterns, "-hyphen-min-left", "1", "-hyphen-min-right", "1", "-hyphen", "="}
+	if status := run(args, strings.NewReader("abababab\n"), &stdout, &stderr); status != 0 {
+		t.Fatalf("got status %d: %s", status, stderr.String())
+	}
+	if got, want := stdout.String(), "ababa=\nbab\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	stderr.Reset()
+	if status := run([]string{"-hyphenation-patterns", invalid}, nil, &stdout, &stderr); status != 2 {
+		t.Errorf("got status %d for invalid patterns, want 2", status)
+	}
+	if got, want := stderr.String(), "wrap: "+invalid+": line 1: "; !strings.HasPrefix(got, want) {
+		t.Errorf("got errors %q, want them to start with %q", got, want)
+	}
+}
+
+func TestRun_Files(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	if err := os.WriteFile(a, []byte("one two three four\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("five six\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"-w", "10", a, b}, nil, &stdout, &stderr); status != 0 {
+		t.Fatalf("got status %d: %s", status, stderr.String())
+	}
+	if got, want := stdout.String(), "one two\nthree four\nfive six\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	stdout.Reset()
+	if status := run([]string{"-w", "10", "-check", a, b}, nil, &stdout, &stderr); status != 1 {
+		t.Errorf("got check status %d, want 1", status)
+	}
+	if got, want := stdout.String(), a+":1: line exceeds 10 columns\n"; got != want {
+		t.Errorf("got check output %q, want %q", got, want)
+	}
+
+	stdout.Reset()
+	if status := run([]string{"-w", "10", "-i", a, b}, nil, &stdout, &stderr); status != 0 {
+		t.Fatalf("got in place status %d: %s", status, stderr.String())
+	}
+	if stdout.Len() > 0 {
+		t.Errorf("got output %q when editing in place", stdout.String())
+	}
+	got, err := os.ReadFile(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "one two\nthree four\n"; string(got) != want {
+		t.Errorf("got file %q, want %q", got, want)
+	}
+	for name, perm := range map[string]os.FileMode{a: 0o600, b: 0o644} {
+		if info, err := os.Stat(name); err != nil || info.Mode().Perm() != perm {
+			t.Errorf("mode of %s wasn't kept: %v, %v", name, info.Mode(), err)
+		}
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 2 {
+		t.Errorf("got %d files after editing in place, want 2: %v", len(entries), err)
+	}
+
+	link := filepath.Join(dir, "link.txt")
+	if err := os.Symlink(b, link); err == nil {
+		if err := os.WriteFile(b, []byte("seven eight nine\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if status := run([]string{"-w", "10", "-i", link}, nil, &stdout, &stderr); status != 0 {
+			t.Fatalf("got in place status %d for a link: %s", status, stderr.String())
+		}
+		if got, err := os.ReadFile(b); err != nil || string(got) != "seven\neight nine\n" {
+			t.Errorf("got linked file %q, want it rewritten: %v", got, err)
+		}
+		if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("link was replaced: %v", err)
+		}
+	}
+
+	stderr.Reset()
+	if status := run([]string{filepath.Join(dir, "missing.txt"), b}, nil, &stdout, &stderr); status != 1 {
+		t.Errorf("got status %d for a missing file, want 1", status)
+	}
+	if !strings.Contains(stderr.String(), "missing.txt") {
+		t.Errorf("got errors %q, want the missing file to be reported", stderr.String())
+	}
+}
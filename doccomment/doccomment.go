@@ -0,0 +1,297 @@
+//go:build go1.19
+
+// Package doccomment reflows Go doc comments. Comments are parsed with
+// go/doc/comment, so that only the text of paragraphs and list items is
+// wrapped by a wrap.Wrapper, while code blocks, headings and link definitions
+// are written back unchanged.
+package doccomment
+
+import (
+	"go/ast"
+	"go/doc/comment"
+	"strings"
+
+	"github.com/bbrks/wrap/v2"
+)
+
+// Style selects the comment markers written by a Formatter.
+type Style int
+
+const (
+	// StyleInput writes the same kind of comment that was read, or line
+	// comments if the input had no comment markers.
+	StyleInput Style = iota
+	// StyleLine writes a group of // line comments.
+	StyleLine
+	// StyleBlock writes a /* */ block comment.
+	StyleBlock
+)
+
+// Formatter contains settings for reflowing Go doc comments.
+type Formatter struct {
+	// Wrapper wraps the text of paragraphs and list items. Its prefixes,
+	// suffixes and input trimming are replaced by the comment markers and
+	// list indentation written by the Formatter, and URLs are always
+	// protected, so that links aren't broken at their hyphens.
+	// Default: wrap.NewWrapper()
+	Wrapper wrap.Wrapper
+
+	// Style selects whether to write // line comments or a /* */ block
+	// comment.
+	// Default: StyleInput
+	Style Style
+
+	// Column is the column that each line of the comment starts at. The
+	// comment is indented with as many tabs as fit, followed by spaces.
+	// Default: 0
+	Column int
+
+	// TabWidth is the number of columns a tab advances to, used both to
+	// indent the comment to Column and to measure that indent against the
+	// limit. If less than 1, the comment is indented with spaces only.
+	// Default: 8
+	TabWidth int
+}
+
+// NewFormatter returns a new instance of a Formatter initialised with
+// defaults.
+func NewFormatter() Formatter {
+	return Formatter{
+		Wrapper:  wrap.NewWrapper(),
+		TabWidth: 8,
+	}
+}
+
+// Format is shorthand for declaring a new default Formatter calling its Format
+// method.
+func Format(src string, limit int) string {
+	return NewFormatter().Format(src, limit)
+}
+
+// Format reflows the Go comment src, which is either a group of // line
+// comments, a /* */ block comment, or comment text without any markers. Each
+// line of the result, including its indent and comment markers, is no wider
+// than limit where the text allows. If limit is less than 1, text is left
+// unwrapped. Directives like //go:generate are kept at the end of the
+// comment.
+func (f Formatter) Format(src string, limit int) string {
+	text, style, directives := parseComment(src)
+	if f.Style != StyleInput {
+		style = f.Style
+	}
+
+	indent := f.indent()
+	marker := "// "
+	if style == StyleBlock {
+		marker = ""
+	}
+	lines := f.render(new(comment.Parser).Parse(text), limit-f.Column-len(marker))
+
+	nl := f.Wrapper.Newline
+	if nl == "" {
+		nl = "\n"
+	}
+	var sb strings.Builder
+	if style == StyleBlock {
+		sb.WriteString(indent + "/*" + nl)
+	}
+	for _, line := range lines {
+		switch {
+		case line == "" && style == StyleBlock:
+		case line == "":
+			sb.WriteString(indent + "//")
+		case strings.HasPrefix(line, "\t") && style == StyleLine:
+			// Code lines keep their tab in place of the space
+			sb.WriteString(indent + "//" + line)
+		default:
+			sb.WriteString(indent + marker + line)
+		}
+		sb.WriteString(nl)
+	}
+	if style == StyleBlock {
+		sb.WriteString(indent + "*/" + nl)
+	}
+	for i, d := range directives {
+		if i == 0 && style == StyleLine && len(lines) > 0 {
+			sb.WriteString(indent + "//" + nl)
+		}
+		sb.WriteString(indent + d + nl)
+	}
+	return sb.String()
+}
+
+// indent returns the whitespace that indents a line to f.Column.
+func (f Formatter) indent() string {
+	if f.Column < 1 {
+		return ""
+	}
+	if f.TabWidth < 1 {
+		return strings.Repeat(" ", f.Column)
+	}
+	return strings.Repeat("\t", f.Column/f.TabWidth) + strings.Repeat(" ", f.Column%f.TabWidth)
+}
+
+// render returns the lines of the parsed comment doc without comment markers,
+// laid out as by comment.Printer but with text wrapped at limit.
+func (f Formatter) render(doc *comment.Doc, limit int) []string {
+	w := f.Wrapper
+	w.OutputLinePrefix, w.OutputLineSuffix = "", ""
+	w.FirstLinePrefix, w.ContinuationPrefix = "", ""
+	w.TrimInputPrefix, w.TrimInputSuffix = "", ""
+	w.PreserveIndent = false
+	w.Protect.URLs = true
+	w.StripTrailingNewline = true
+	w.LimitIncludesPrefixSuffix = true
+	if w.Newline == "" {
+		w.Newline = "\n"
+	}
+	wrapText := func(w wrap.Wrapper, x []comment.Text) []string {
+		return strings.Split(w.Wrap(plainText(x), limit), w.Newline)
+	}
+
+	var lines []string
+	for i, block := range doc.Content {
+		if list, ok := block.(*comment.List); i > 0 && (!ok || list.BlankBefore()) {
+			lines = append(lines, "")
+		}
+		switch x := block.(type) {
+		case *comment.Paragraph:
+			lines = append(lines, wrapText(w, x.Text)...)
+
+		case *comment.Heading:
+			lines = append(lines, "# "+plainText(x.Text))
+
+		case *comment.Code:
+			for _, line := range strings.Split(strings.TrimSuffix(x.Text, "\n"), "\n") {
+				if line != "" {
+					line = "\t" + line
+				}
+				lines = append(lines, line)
+			}
+
+		case *comment.List:
+			loose := x.BlankBetween()
+			for j, item := range x.Items {
+				if j > 0 && loose {
+					lines = append(lines, "")
+				}
+				iw := w
+				iw.FirstLinePrefix, iw.ContinuationPrefix = "  - ", "    "
+				if item.Number != "" {
+					iw.FirstLinePrefix = " " + item.Number + ". "
+				}
+				for k, content := range item.Content {
+					if k > 0 {
+						iw.FirstLinePrefix = iw.ContinuationPrefix
+					}
+					lines = append(lines, wrapText(iw, content.(*comment.Paragraph).Text)...)
+				}
+			}
+		}
+	}
+
+	if len(doc.Links) > 0 {
+		lines = append(lines, "")
+		for _, used := range []bool{true, false} {
+			for _, def := range doc.Links {
+				if def.Used == used {
+					lines = append(lines, "["+def.Text+"]: "+def.URL)
+				}
+			}
+		}
+	}
+	return lines
+}
+
+// plainText returns the source text of x with its line breaks replaced by
+// spaces.
+func plainText(x []comment.Text) string {
+	var sb strings.Builder
+	for _, t := range x {
+		switch t := t.(type) {
+		case comment.Plain:
+			sb.WriteString(string(t))
+		case comment.Italic:
+			sb.WriteString(string(t))
+		case *comment.Link:
+			if t.Auto {
+				sb.WriteString(plainText(t.Text))
+			} else {
+				sb.WriteString("[" + plainText(t.Text) + "]")
+			}
+		case *comment.DocLink:
+			sb.WriteString("[" + plainText(t.Text) + "]")
+		}
+	}
+	return strings.ReplaceAll(sb.String(), "\n", " ")
+}
+
+// parseComment returns the text of the comment src, the style it was written
+// in and any directives it contained.
+func parseComment(src string) (text string, style Style, directives []string) {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	trimmed := strings.TrimLeft(src, " \t\n")
+	switch {
+	case strings.HasPrefix(trimmed, "/*"):
+		// Remove the indent of the opening line from the lines after it
+		first := strings.TrimLeft(src, "\n")
+		indent := first[:len(first)-len(strings.TrimLeft(first, " \t"))]
+		block, rest := trimmed, ""
+		if end := strings.Index(trimmed, "*/"); end >= 0 {
+			block, rest = trimmed[:end+2], trimmed[end+2:]
+		}
+		lines := strings.Split(block, "\n")
+		for i := range lines {
+			lines[i] = strings.TrimPrefix(lines[i], indent)
+		}
+		for _, line := range strings.Split(rest, "\n") {
+			if line = strings.TrimSpace(line); isDirective(line) {
+				directives = append(directives, line)
+			}
+		}
+		group := ast.CommentGroup{List: []*ast.Comment{{Text: strings.Join(lines, "\n")}}}
+		return group.Text(), StyleBlock, directives
+
+	case strings.HasPrefix(trimmed, "//"):
+		var group ast.CommentGroup
+		for _, line := range strings.Split(strings.TrimSpace(src), "\n") {
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, "//") {
+				line = "// " + line
+			}
+			if isDirective(line) {
+				directives = append(directives, line)
+			}
+			group.List = append(group.List, &ast.Comment{Text: line})
+		}
+		return group.Text(), StyleLine, directives
+	}
+	return src, StyleLine, nil
+}
+
+// isDirective reports whether the line comment c is a directive, like
+// //go:generate or //export, that must be kept as it is.
+func isDirective(c string) bool {
+	if !strings.HasPrefix(c, "//") {
+		return false
+	}
+	c = c[2:]
+	for _, prefix := range []string{"line ", "extern ", "export "} {
+		if strings.HasPrefix(c, prefix) {
+			return true
+		}
+	}
+	colon := strings.Index(c, ":")
+	if colon <= 0 || colon+1 >= len(c) {
+		return false
+	}
+	for i := 0; i <= colon+1; i++ {
+		if i == colon {
+			continue
+		}
+		if b := c[i]; !('a' <= b && b <= 'z' || '0' <= b && b <= '9') {
+			return false
+		}
+	}
+	return true
+}
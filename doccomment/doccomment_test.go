@@ -0,0 +1,143 @@
+//go:build go1.19
+
+package doccomment_test
+
+import (
+	"testing"
+
+	"github.com/bbrks/wrap/v2"
+	"github.com/bbrks/wrap/v2/doccomment"
+)
+
+func TestFormatter_Format(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		options  func(f *doccomment.Formatter)
+		expected string
+	}{
+		{
+			name:     "paragraphs",
+			input:    "// The quick brown fox jumps over the lazy dog.\n//\n// Second paragraph.",
+			limit:    20,
+			expected: "// The quick brown\n// fox jumps over\n// the lazy dog.\n//\n// Second paragraph.\n",
+		},
+		{
+			name:     "code block",
+			input:    "// Example:\n//\n//\tfmt.Println(\"a long line of code that stays put\")\n//\n//\tif x {\n//\t\treturn\n//\t}",
+			limit:    20,
+			expected: "// Example:\n//\n//\tfmt.Println(\"a long line of code that stays put\")\n//\n//\tif x {\n//\t\treturn\n//\t}\n",
+		},
+		{
+			name:     "heading",
+			input:    "// Intro.\n//\n// # A heading that is long\n//\n// Text.",
+			limit:    15,
+			expected: "// Intro.\n//\n// # A heading that is long\n//\n// Text.\n",
+		},
+		{
+			name:     "lists",
+			input:    "// Items:\n//   - one two three four five\n//   - six\n//\n// Steps:\n//  1. seven eight nine ten\n//  2. eleven",
+			limit:    20,
+			expected: "// Items:\n//   - one two three\n//     four five\n//   - six\n//\n// Steps:\n//  1. seven eight\n//     nine ten\n//  2. eleven\n",
+		},
+		{
+			name:     "links",
+			input:    "// See [io.Reader], [the docs] and https://go.dev/doc/comment.\n//\n// [the docs]: https://go.dev/doc",
+			limit:    20,
+			expected: "// See [io.Reader],\n// [the docs] and\n// https://go.dev/doc/comment.\n//\n// [the docs]: https://go.dev/doc\n",
+		},
+		{
+			name:     "hyphenated url",
+			input:    "// Read https://example.com/a-very-long-url-path for more.",
+			limit:    20,
+			expected: "// Read\n// https://example.com/a-very-long-url-path\n// for more.\n",
+		},
+		{
+			name:     "directives",
+			input:    "//go:generate stringer -type=Kind\n// Kind is the kind of a thing.",
+			limit:    20,
+			expected: "// Kind is the kind\n// of a thing.\n//\n//go:generate stringer -type=Kind\n",
+		},
+		{
+			name:     "block comment",
+			input:    "\t/*\n\tPackage foo does a thing with text.\n\n\t\tfoo.Do()\n\t*/",
+			limit:    20,
+			options:  func(f *doccomment.Formatter) { f.Column = 8 },
+			expected: "\t/*\n\tPackage foo\n\tdoes a thing\n\twith text.\n\n\t\tfoo.Do()\n\t*/\n",
+		},
+		{
+			name:     "line to block comment",
+			input:    "// Package foo does a thing with text.",
+			limit:    20,
+			options:  func(f *doccomment.Formatter) { f.Style = doccomment.StyleBlock },
+			expected: "/*\nPackage foo does a\nthing with text.\n*/\n",
+		},
+		{
+			name:     "block to line comment",
+			input:    "/* Package foo does a thing with text. */",
+			limit:    20,
+			options:  func(f *doccomment.Formatter) { f.Style = doccomment.StyleLine },
+			expected: "// Package foo does\n// a thing with\n// text.\n",
+		},
+		{
+			name:  "column",
+			input: "\t// The quick brown fox jumps over the lazy dog.",
+			limit: 30,
+			options: func(f *doccomment.Formatter) {
+				f.Column = 10
+			},
+			expected: "\t  // The quick brown\n\t  // fox jumps over\n\t  // the lazy dog.\n",
+		},
+		{
+			name:  "column with spaces",
+			input: "// The quick brown fox jumps over the lazy dog.",
+			limit: 24,
+			options: func(f *doccomment.Formatter) {
+				f.Column, f.TabWidth = 4, 0
+			},
+			expected: "    // The quick brown\n    // fox jumps over\n    // the lazy dog.\n",
+		},
+		{
+			name:     "no markers",
+			input:    "The quick brown fox jumps over the lazy dog.",
+			limit:    20,
+			expected: "// The quick brown\n// fox jumps over\n// the lazy dog.\n",
+		},
+		{
+			name:  "wrapper settings",
+			input: "// The quick brown fox jumps over the lazy dog.",
+			limit: 20,
+			options: func(f *doccomment.Formatter) {
+				f.Wrapper.Alignment = wrap.AlignJustify
+				f.Wrapper.Newline = "\r\n"
+			},
+			expected: "// The  quick  brown\r\n// fox   jumps  over\r\n// the lazy dog.\r\n",
+		},
+		{
+			name:     "unwrapped",
+			input:    "// The quick brown\n// fox jumps over\n// the lazy dog.",
+			limit:    0,
+			expected: "// The quick brown fox jumps over the lazy dog.\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := doccomment.NewFormatter()
+			if tt.options != nil {
+				tt.options(&f)
+			}
+
+			got := f.Format(tt.input, tt.limit)
+			if got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+
+			// Formatting is stable
+			if again := f.Format(got, tt.limit); again != got {
+				t.Errorf("formatting again got %q, want %q", again, got)
+			}
+		})
+	}
+}
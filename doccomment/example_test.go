@@ -0,0 +1,32 @@
+//go:build go1.19
+
+package doccomment_test
+
+import (
+	"fmt"
+
+	"github.com/bbrks/wrap/v2/doccomment"
+)
+
+func ExampleFormat() {
+	var src = `// Wrap wraps text at the given limit, like this call to [strings.Fields] would never do. It is used like so:
+//
+//	wrapped := wrap.Wrap("some long text that needs wrapping", 10)
+//
+// The options are:
+//   - Breakpoints, to choose where lines can break
+//   - Newline, to choose how lines end`
+
+	fmt.Print(doccomment.Format(src, 40))
+	// Output:
+	// // Wrap wraps text at the given limit,
+	// // like this call to [strings.Fields]
+	// // would never do. It is used like so:
+	// //
+	// //	wrapped := wrap.Wrap("some long text that needs wrapping", 10)
+	// //
+	// // The options are:
+	// //   - Breakpoints, to choose where
+	// //     lines can break
+	// //   - Newline, to choose how lines end
+}
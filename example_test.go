@@ -2,6 +2,9 @@ package wrap_test
 
 import (
 	"fmt"
+	"math"
+	"os"
+	"strings"
 
 	"github.com/bbrks/wrap/v2"
 )
@@ -214,6 +217,58 @@ func ExampleWrapper_Wrap_hyphens() {
 	// brainer.
 }
 
+func ExampleWrapper_Wrap_hyphenation() {
+	w := wrap.NewWrapper()
+	w.Hyphenation.Hyphenator = wrap.HyphenatorEnUS()
+
+	fmt.Print(w.Wrap("The international hyphenation algorithm breaks paragraphs nicely.", 14))
+	// Output:
+	// The interna-
+	// tional hyphen-
+	// ation algo-
+	// rithm breaks
+	// paragraphs
+	// nicely.
+}
+
+func ExampleWrapper_Wrap_protect() {
+	w := wrap.NewWrapper()
+	w.Protect = wrap.ProtectOptions{URLs: true, Code: true}
+
+	fmt.Print(w.Wrap("Install it from https://github.com/bbrks/wrap-cli and run `wrap -w 40` to try it.", 24))
+	// Output:
+	// Install it from
+	// https://github.com/bbrks/wrap-cli
+	// and run `wrap -w 40` to
+	// try it.
+}
+
+func ExampleWrapper_Wrap_tabs() {
+	w := wrap.NewWrapper()
+	w.OutputLinePrefix = "# "
+	w.ContinuationPrefix = "\t"
+	w.Tabs.Width = 8
+
+	fmt.Print(w.Wrap("wrap\twraps each line of text to the width\ncheck\treports lines that are too wide", 32))
+	// Output:
+	// # wrap  wraps each line of text
+	// #       to the width
+	// # check reports lines that are
+	// #       too wide
+}
+
+func ExampleHyphenator_Hyphenate() {
+	word := "hyphenation"
+	prev := 0
+	for _, i := range wrap.HyphenatorEnUS().Hyphenate(word, 2, 3) {
+		fmt.Print(word[prev:i], "-")
+		prev = i
+	}
+	fmt.Println(word[prev:])
+	// Output:
+	// hy-phen-a-tion
+}
+
 func ExampleWrapper_Wrap_prefix() {
 	var loremIpsum = "/* Lorem ipsum dolor sit amet, consectetur adipiscing elit. Sed vulputate quam nibh, et faucibus enim gravida vel. Integer bibendum lectus et erat semper fermentum quis a risus. Fusce dignissim tempus metus non pretium. Nunc sagittis magna nec purus porttitor mollis. Pellentesque feugiat quam eget laoreet aliquet. Donec gravida congue massa, et sollicitudin turpis lacinia a. Fusce non tortor magna. Cras vel finibus tellus. */"
 
@@ -237,6 +292,40 @@ func ExampleWrapper_Wrap_prefix() {
 	// // a. Fusce non tortor magna. Cras vel finibus tellus.
 }
 
+func ExampleWrapper_Wrap_hangingIndent() {
+	var items = "Breakpoints are the characters lines may be broken at.\nNewline is used to split input lines and join output lines."
+
+	w := wrap.NewWrapper()
+	w.StripTrailingNewline = true
+	w.FirstLinePrefix = "- "
+	w.ContinuationPrefix = "  "
+	fmt.Println(w.Wrap(items, 30))
+	// Output:
+	// - Breakpoints are the
+	//   characters lines may be
+	//   broken at.
+	// - Newline is used to split
+	//   input lines and join output
+	//   lines.
+}
+
+func ExampleWrapper_Wrap_preserveIndent() {
+	var notes = "Release notes:\n  1. Lines can now be justified, centered or right aligned.\n  2. Lists are reflowed:\n     - markers and indents are kept on the first line."
+
+	w := wrap.NewWrapper()
+	w.StripTrailingNewline = true
+	w.PreserveIndent = true
+	fmt.Println(w.Wrap(notes, 30))
+	// Output:
+	// Release notes:
+	//   1. Lines can now be
+	//      justified, centered or
+	//      right aligned.
+	//   2. Lists are reflowed:
+	//      - markers and indents are
+	//        kept on the first line.
+}
+
 func ExampleWrapper_Wrap_minimumRaggedness() {
 	// This example demonstrates the difference between greedy and optimal wrapping.
 	// The input is designed to show how optimal wrapping produces more balanced lines.
@@ -264,3 +353,249 @@ func ExampleWrapper_Wrap_minimumRaggedness() {
 	// i j k l
 	// m n o p
 }
+
+func ExampleWrapper_Wrap_balanced() {
+	w := wrap.NewWrapper()
+	w.Algorithm = wrap.AlgorithmBalanced
+
+	fmt.Print(w.Wrap("Getting started with the wrap package", 30))
+	// Output:
+	// Getting started with
+	// the wrap package
+}
+
+func ExampleWrapper_Wrap_knuthPlass() {
+	// The Knuth–Plass algorithm avoids ending consecutive lines with hyphens.
+	var text = "up-to-date mother-in-law ex-president"
+
+	w := wrap.NewWrapper()
+	w.StripTrailingNewline = true
+	w.Algorithm = wrap.AlgorithmKnuthPlass
+	fmt.Println(w.Wrap(text, 10))
+	// Output:
+	// up-to-date
+	// mother-
+	// in-law
+	// ex-
+	// president
+}
+
+func ExampleWrapper_Wrap_justify() {
+	var loremIpsum = "Lorem ipsum dolor sit amet, consectetur adipiscing elit. Sed vulputate quam nibh, et faucibus enim gravida vel."
+
+	w := wrap.NewWrapper()
+	w.StripTrailingNewline = true
+	w.Alignment = wrap.AlignJustify
+	w.OutputLinePrefix = "/* "
+	w.OutputLineSuffix = " */"
+	fmt.Println(w.Wrap(loremIpsum, 36))
+	// Output:
+	// /* Lorem  ipsum  dolor  sit amet, */
+	// /* consectetur  adipiscing  elit. */
+	// /* Sed  vulputate  quam  nibh, et */
+	// /* faucibus enim gravida vel.     */
+}
+
+func ExampleWrapper_Wrap_center() {
+	w := wrap.NewWrapper()
+	w.StripTrailingNewline = true
+	w.Alignment = wrap.AlignCenter
+	w.OutputLinePrefix = "| "
+	w.OutputLineSuffix = " |"
+	fmt.Println(w.Wrap("Welcome to wrap\nA library for wrapping text", 24))
+	// Output:
+	// |   Welcome to wrap    |
+	// |    A library for     |
+	// |    wrapping text     |
+}
+
+func ExampleWrapper_Wrap_maxLines() {
+	w := wrap.NewWrapper()
+	w.MaxLines = 2
+
+	fmt.Print(w.Wrap("The quick brown fox jumps over the lazy dog.", 12))
+	// Output:
+	// The quick
+	// brown fox…
+}
+
+func ExampleWrapper_Truncate() {
+	w := wrap.NewWrapper()
+	w.Breakpoints = "/"
+	w.TruncateAtBreakpoints = true
+
+	path := "/home/user/projects/wrap/main.go"
+	for _, mode := range []wrap.TruncateMode{wrap.TruncateEnd, wrap.TruncateStart, wrap.TruncateMiddle} {
+		w.TruncateMode = mode
+		fmt.Println(w.Truncate(path, 20))
+	}
+	// Output:
+	// /home/user/projects…
+	// …/wrap/main.go
+	// /home/user…/main.go
+}
+
+func ExampleWrapper_WidthForLines() {
+	w := wrap.NewWrapper()
+	s := "The quick brown fox jumps over the lazy dog."
+
+	limit := w.WidthForLines(s, 3)
+	fmt.Println(limit, w.LineCount(s, limit))
+	fmt.Print(w.Wrap(s, limit))
+	// Output:
+	// 15 3
+	// The quick brown
+	// fox jumps over
+	// the lazy dog.
+}
+
+func ExampleWrapper_Wrap_displayWidth() {
+	var text = "日本語の文章は 全角文字で 書かれています"
+
+	w := wrap.NewWrapper()
+	w.StripTrailingNewline = true
+
+	// Measure lines in terminal columns, so each wide character counts as two.
+	w.DisplayWidth = true
+
+	fmt.Println(w.Wrap(text, 16))
+	// Output:
+	// 日本語の文章は
+	// 全角文字で
+	// 書かれています
+}
+
+func ExampleWrapper_Wrap_measurer() {
+	// Advances in points of some glyphs at 10pt, as a PDF library might give
+	advances := map[string]float64{"i": 2.22, "l": 2.22, "m": 8.33, "w": 7.22, " ": 2.78}
+
+	w := wrap.NewWrapper()
+	// Measure in thousandths of a point, so that the limit is 40pt
+	w.Measurer = wrap.MeasurerFunc(func(s string) int {
+		return int(math.Round(advances[s] * 1000))
+	})
+
+	fmt.Print(w.Wrap("will ill mill wim ill will mm mmm", 40*1000))
+	// Output:
+	// will ill
+	// mill wim
+	// ill will
+	// mm
+	// mmm
+}
+
+func ExampleWrapper_Wrap_reapplyStyles() {
+	var text = "\x1b[31mThis whole sentence is red.\x1b[0m"
+
+	w := wrap.NewWrapper()
+	w.StripTrailingNewline = true
+	w.OutputLinePrefix = "> "
+
+	// Treat escape sequences as zero-width, and keep the color off the prefix.
+	w.ReapplyStyles = true
+
+	for _, line := range strings.Split(w.Wrap(text, 16), "\n") {
+		fmt.Printf("%q\n", line)
+	}
+	// Output:
+	// "> \x1b[31mThis whole\x1b[0m"
+	// "> \x1b[31msentence is\x1b[0m"
+	// "> \x1b[31mred.\x1b[0m"
+}
+
+func ExampleWrapper_Wrap_unicodeLineBreaking() {
+	var text = "The range 10-20 (inclusive) costs $5.00 per item."
+
+	w := wrap.NewWrapper()
+
+	// Break where the Unicode Line Breaking Algorithm allows, rather than at
+	// every space and hyphen.
+	w.UnicodeLineBreaking = true
+
+	fmt.Println(w.Wrap(text, 12))
+	// Output:
+	// The range
+	// 10-20
+	// (inclusive)
+	// costs $5.00
+	// per item.
+}
+
+func ExampleReflow() {
+	var wrapped = "Lorem ipsum dolor sit\namet, consectetur\nadipiscing elit.\n\nSed vulputate quam nibh,\net faucibus enim gravida\nvel."
+
+	fmt.Println(wrap.Reflow(wrapped, 40))
+	// Output:
+	// Lorem ipsum dolor sit amet, consectetur
+	// adipiscing elit.
+	//
+	// Sed vulputate quam nibh, et faucibus
+	// enim gravida vel.
+}
+
+func ExampleWrapper_Reflow() {
+	var comment = "// Lorem ipsum dolor sit amet,\n// consectetur adipiscing elit. Sed\n// vulputate quam nibh, et faucibus\n// enim gravida vel."
+
+	w := wrap.NewWrapper()
+	w.StripTrailingNewline = true
+	w.TrimInputPrefix = "// "
+	w.OutputLinePrefix = "// "
+	fmt.Println(w.Reflow(comment, 60))
+	// Output:
+	// // Lorem ipsum dolor sit amet, consectetur adipiscing elit.
+	// // Sed vulputate quam nibh, et faucibus enim gravida vel.
+}
+
+func ExampleWrapMarkdown() {
+	var doc = "# Usage\n\nRun `go test ./...` to check the package, or read [the docs](https://pkg.go.dev/github.com/bbrks/wrap/v2).\n\n- The first item in a list that needs wrapping\n- Second\n\n```sh\ngo get github.com/bbrks/wrap/v2\n```"
+
+	fmt.Print(wrap.WrapMarkdown(doc, 30))
+	// Output:
+	// # Usage
+	//
+	// Run `go test ./...` to check
+	// the package, or read [the
+	// docs](https://pkg.go.dev/github.com/bbrks/wrap/v2).
+	//
+	// - The first item in a list
+	//   that needs wrapping
+	// - Second
+	//
+	// ```sh
+	// go get github.com/bbrks/wrap/v2
+	// ```
+}
+
+func ExampleWrapper_NewWriter() {
+	w := wrap.NewWrapper()
+	w.OutputLinePrefix = "| "
+
+	// Lines are written to os.Stdout as soon as they're decided.
+	wr := w.NewWriter(os.Stdout, 16)
+	fmt.Fprint(wr, "Text can be written in ")
+	fmt.Fprint(wr, "any number of chunks.")
+	wr.Close()
+	// Output:
+	// | Text can be
+	// | written in any
+	// | number of
+	// | chunks.
+}
+
+func ExampleWrapper_Lines() {
+	w := wrap.NewWrapper()
+	w.CutLongWords = true
+
+	s := "The quick brown fox jumped over the abcdefghijklmnopqrstuvwxyz"
+	for _, line := range w.Lines(s, 12) {
+		fmt.Printf("%2d-%-2d %-10s %q\n", line.Start, line.End, line.Break, line.Text)
+	}
+	// Output:
+	//  0-9  breakpoint "The quick"
+	// 10-19 breakpoint "brown fox"
+	// 20-31 breakpoint "jumped over"
+	// 32-35 breakpoint "the"
+	// 36-48 cut        "abcdefghijkl"
+	// 48-60 cut        "mnopqrstuvwx"
+	// 60-62 end        "yz"
+}
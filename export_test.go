@@ -0,0 +1,18 @@
+package wrap
+
+// StringWidth exposes stringWidth to external tests.
+func (w Wrapper) StringWidth(s string) int {
+	ww := newWrapping(w)
+	return ww.stringWidth(s)
+}
+
+// Graphemes splits s into its extended grapheme clusters for external tests.
+func Graphemes(s string) []string {
+	var clusters []string
+	for s != "" {
+		n := nextGrapheme(s)
+		clusters = append(clusters, s[:n])
+		s = s[n:]
+	}
+	return clusters
+}
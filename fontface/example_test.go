@@ -0,0 +1,36 @@
+package fontface_test
+
+import (
+	"fmt"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/bbrks/wrap/v2"
+	"github.com/bbrks/wrap/v2/fontface"
+)
+
+func Example() {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		panic(err)
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: 12, DPI: 72})
+	if err != nil {
+		panic(err)
+	}
+	defer face.Close()
+
+	w := wrap.NewWrapper()
+	w.Measurer = fontface.New(face)
+
+	// Narrow letters take up less of the 100 pixels than wide ones
+	for _, line := range w.Lines("illicit illicit illicit MMM WWW MMM WWW", fontface.Pixels(100)) {
+		fmt.Printf("%s (%dpx)\n", line.Text, fixed.Int26_6(line.Width).Ceil())
+	}
+	// Output:
+	// illicit illicit illicit (81px)
+	// MMM WWW (68px)
+	// MMM WWW (68px)
+}
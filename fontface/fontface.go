@@ -0,0 +1,40 @@
+// Package fontface measures text with a font.Face from golang.org/x/image, so
+// that a wrap.Wrapper can wrap text set in a proportional font to a width in
+// pixels. It's a separate module so that the wrap module itself doesn't
+// depend on golang.org/x/image.
+package fontface
+
+import (
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Measurer is a wrap.Measurer that measures text by the advances of its
+// glyphs in Face, in 26.6 fixed-point pixels. Limits and other widths given
+// to a Wrapper using it are in the same units, as returned by Pixels, and the
+// widths it returns, such as the Width of a wrap.Line, convert back with
+// fixed.Int26_6.
+//
+// A font.Face usually isn't safe for concurrent use, and neither is a Wrapper
+// with a Measurer using one.
+type Measurer struct {
+	// Face is the font face that glyphs are measured in.
+	Face font.Face
+}
+
+// New returns a Measurer for face.
+func New(face font.Face) Measurer {
+	return Measurer{Face: face}
+}
+
+// Width returns the advance of s in 26.6 fixed-point pixels, including any
+// kerning between its runes.
+func (m Measurer) Width(s string) int {
+	return int(font.MeasureString(m.Face, s))
+}
+
+// Pixels returns a width of px pixels in the units of a Measurer, for use as
+// a limit.
+func Pixels(px int) int {
+	return int(fixed.I(px))
+}
@@ -0,0 +1,72 @@
+package fontface_test
+
+import (
+	"testing"
+
+	"golang.org/x/image/font/basicfont"
+
+	"github.com/bbrks/wrap/v2"
+	"github.com/bbrks/wrap/v2/fontface"
+)
+
+func TestMeasurer_Width(t *testing.T) {
+	m := fontface.New(basicfont.Face7x13)
+
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"", 0},
+		{"a", fontface.Pixels(7)},
+		{"abc", fontface.Pixels(21)},
+	}
+
+	for _, tt := range tests {
+		if got := m.Width(tt.input); got != tt.expected {
+			t.Errorf("Width(%q) = %d, want %d", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestMeasurer_Wrap(t *testing.T) {
+	w := wrap.NewWrapper()
+	w.Measurer = fontface.New(basicfont.Face7x13)
+
+	tests := []struct {
+		name     string
+		options  func(w *wrap.Wrapper)
+		expected string
+	}{
+		{
+			name:     "greedy",
+			expected: "The quick\nbrown fox\njumps over\nthe lazy\ndog.\n",
+		},
+		{
+			name:     "minimum raggedness",
+			options:  func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmMinimumRaggedness },
+			expected: "The quick\nbrown\nfox jumps\nover the\nlazy dog.\n",
+		},
+		{
+			name:     "knuth-plass",
+			options:  func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmKnuthPlass },
+			expected: "The quick\nbrown fox\njumps over\nthe lazy\ndog.\n",
+		},
+		{
+			name:     "right",
+			options:  func(w *wrap.Wrapper) { w.Alignment = wrap.AlignRight },
+			expected: " The quick\n brown fox\njumps over\n  the lazy\n      dog.\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := w
+			if tt.options != nil {
+				tt.options(&w)
+			}
+			if got := w.Wrap("The quick brown fox jumps over the lazy dog.", fontface.Pixels(70)); got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/bbrks/wrap/v2/fontface
+
+go 1.18
+
+require (
+	github.com/bbrks/wrap/v2 v2.0.0
+	golang.org/x/image v0.18.0
+)
+
+require golang.org/x/text v0.16.0 // indirect
+
+replace github.com/bbrks/wrap/v2 => ../
@@ -1,6 +1,7 @@
 package wrap_test
 
 import (
+	"regexp"
 	"strings"
 	"testing"
 	"unicode/utf8"
@@ -92,9 +93,13 @@ func FuzzWrap(f *testing.F) {
 		}
 
 		// With CutLongWords and positive limit, lines should not exceed limit by more than 1
-		// (the +1 accounts for preserved hyphens at line breaks)
+		// (the +1 accounts for preserved hyphens at line breaks), unless the line is a
+		// single grapheme cluster which can't be cut
 		if limit > 0 {
 			for _, line := range strings.Split(strings.TrimSuffix(result3, "\n"), "\n") {
+				if len(wrap.Graphemes(line)) == 1 {
+					continue
+				}
 				lineLen := utf8.RuneCountInString(line)
 				// Allow +1 for hyphen preservation at end of line
 				if lineLen > limit+1 {
@@ -103,6 +108,42 @@ func FuzzWrap(f *testing.F) {
 			}
 		}
 
+		// With DisplayWidth and CutLongWords, lines should not exceed the limit in
+		// columns by more than 1, unless a single wide cluster can't fit at all
+		w.DisplayWidth = true
+		result6 := w.Wrap(input, limit)
+		if !utf8.ValidString(result6) {
+			t.Errorf("result with DisplayWidth is not valid UTF-8: %q", result6)
+		}
+		if limit > 0 {
+			for _, line := range strings.Split(strings.TrimSuffix(result6, "\n"), "\n") {
+				if len(wrap.Graphemes(line)) == 1 {
+					continue
+				}
+				lineWidth := w.StringWidth(line)
+				if lineWidth > limit+1 {
+					t.Errorf("line exceeds limit %d by more than 1: %q (width=%d)", limit, line, lineWidth)
+				}
+			}
+		}
+		w.DisplayWidth = false
+
+		// With tabs expanded and CutLongWords, lines should not exceed the limit
+		// by more than 1 either, unless a tab wider than the limit is on its own
+		w.Tabs.Width = 4
+		result7 := w.Wrap(input, limit)
+		if limit > 0 {
+			for _, line := range strings.Split(strings.TrimSuffix(result7, "\n"), "\n") {
+				if len(wrap.Graphemes(line)) == 1 || strings.Trim(line, " ") == "" {
+					continue
+				}
+				if lineLen := utf8.RuneCountInString(line); lineLen > limit+1 {
+					t.Errorf("line with tabs exceeds limit %d by more than 1: %q (len=%d)", limit, line, lineLen)
+				}
+			}
+		}
+		w.Tabs.Width = 0
+
 		// Test with MinimumRaggedness
 		w.CutLongWords = false
 		w.MinimumRaggedness = true
@@ -161,20 +202,121 @@ func FuzzWrapWithOptions(f *testing.F) {
 			t.Errorf("result is not valid UTF-8: %q", result)
 		}
 
-		// Test all option combinations including MinimumRaggedness
+		// Test all option combinations including each algorithm
 		for _, strip := range []bool{false, true} {
 			for _, cut := range []bool{false, true} {
 				for _, includeLimit := range []bool{false, true} {
-					for _, optimal := range []bool{false, true} {
+					for _, algorithm := range []wrap.Algorithm{wrap.AlgorithmGreedy, wrap.AlgorithmMinimumRaggedness, wrap.AlgorithmKnuthPlass, wrap.AlgorithmBalanced} {
 						w.StripTrailingNewline = strip
 						w.CutLongWords = cut
 						w.LimitIncludesPrefixSuffix = includeLimit
-						w.MinimumRaggedness = optimal
+						w.Algorithm = algorithm
 
 						result := w.Wrap(input, limit)
 						if !utf8.ValidString(result) {
-							t.Errorf("result is not valid UTF-8 with strip=%v cut=%v includeLimit=%v optimal=%v: %q",
-								strip, cut, includeLimit, optimal, result)
+							t.Errorf("result is not valid UTF-8 with strip=%v cut=%v includeLimit=%v algorithm=%v: %q",
+								strip, cut, includeLimit, algorithm, result)
+						}
+					}
+				}
+			}
+		}
+	})
+}
+
+func FuzzWrapMarkdown(f *testing.F) {
+	f.Add("# Title\n\nSome `code span` and [a link](https://example.com) here.", 10)
+	f.Add("- item one\n  continued\n- item two\n\n  1. nested", 8)
+	f.Add("> quote\nlazy\n>\n> > nested", 6)
+	f.Add("```\ncode\n```\n\n    indented\n\n| a | b |\n|---|---|", 4)
+	f.Add("<div>\nhtml\n</div>\n\n[ref]: /url", 3)
+	f.Add("text 1. - # > <https://a.b> ``` end  \nhard\\\nbreak", 2)
+	f.Add("-\n  \t\tcode\n*\t* *", 1)
+
+	f.Fuzz(func(t *testing.T, input string, limit int) {
+		if !utf8.ValidString(input) {
+			t.Skip()
+		}
+
+		w := wrap.NewWrapper()
+		for _, algorithm := range []wrap.Algorithm{wrap.AlgorithmGreedy, wrap.AlgorithmMinimumRaggedness, wrap.AlgorithmKnuthPlass, wrap.AlgorithmBalanced} {
+			for _, unicode := range []bool{false, true} {
+				w.Algorithm = algorithm
+				w.UnicodeLineBreaking = unicode
+
+				result := w.WrapMarkdown(input, limit)
+				if !utf8.ValidString(result) {
+					t.Errorf("result is not valid UTF-8 with algorithm=%v unicode=%v: %q", algorithm, unicode, result)
+				}
+			}
+		}
+	})
+}
+
+func FuzzLines(f *testing.F) {
+	f.Add("The quick brown fox jumps over the lazy dog", 10)
+	f.Add("well-known\n  - indented list item", 6)
+	f.Add("abcdefghij klm\n\n日本語のテキスト", 3)
+	f.Add("one\u2028two three", 4)
+	f.Add("The international hyphenation algorithm", 8)
+	f.Add("inter\u00adna\u00adtion\u00adal path/to\u200bfile 10\u00a0km e-\u2060mail", 7)
+	f.Add("See https://example.com/a-b, first-last@example.com or `go test -run X` in ./a-b/c.go", 9)
+	f.Add("\tname\tvalue that wraps\n\t\thyphenation", 9)
+
+	f.Fuzz(func(t *testing.T, input string, limit int) {
+		if !utf8.ValidString(input) {
+			t.Skip()
+		}
+
+		w := wrap.NewWrapper()
+		w.StripTrailingNewline = true
+		for _, algorithm := range []wrap.Algorithm{wrap.AlgorithmGreedy, wrap.AlgorithmMinimumRaggedness, wrap.AlgorithmKnuthPlass, wrap.AlgorithmBalanced} {
+			for _, cut := range []bool{false, true} {
+				for _, unicode := range []bool{false, true} {
+					for _, protect := range []bool{false, true} {
+						w.Algorithm = algorithm
+						w.CutLongWords = cut
+						w.UnicodeLineBreaking = unicode
+						w.PreserveIndent = cut
+						w.Protect = wrap.ProtectOptions{}
+						w.Tabs = wrap.TabOptions{}
+						if protect {
+							w.Protect = wrap.ProtectOptions{URLs: true, Emails: true, Paths: true, Code: true}
+							w.Tabs = wrap.TabOptions{Width: 4, Keep: true}
+						}
+						// Hyphenate half the time, so lines can end with a hyphen
+						w.Hyphenation.Hyphenator = nil
+						if unicode == cut {
+							w.Hyphenation.Hyphenator = wrap.HyphenatorEnUS()
+						}
+
+						lines := w.Lines(input, limit)
+						parts := make([]string, len(lines))
+						prev := 0
+						for i, line := range lines {
+							parts[i] = line.Prefix + line.Text + line.Suffix
+							text := line.Text
+							if line.Break == wrap.BreakHyphen {
+								text = strings.TrimSuffix(text, w.Hyphenation.Hyphen)
+							}
+							if line.Start < prev || line.End < line.Start || line.End > len(input) || input[line.Start:line.End] != text {
+								t.Fatalf("line %d has text %q at %d-%d with algorithm=%v cut=%v unicode=%v protect=%v", i, line.Text, line.Start, line.End, algorithm, cut, unicode, protect)
+							}
+							prev = line.End
+						}
+						if got, want := strings.Join(parts, w.Newline), w.Wrap(input, limit); got != want {
+							t.Fatalf("lines give %q, want %q with algorithm=%v cut=%v unicode=%v protect=%v", got, want, algorithm, cut, unicode, protect)
+						}
+
+						// Measure must agree with the lines, whether it renders them or not
+						width := 0
+						for _, line := range lines {
+							if n := w.StringWidth(line.Prefix) + line.Width + w.StringWidth(line.Suffix); n > width {
+								width = n
+							}
+						}
+						if gotLines, gotWidth := w.Measure(input, limit); gotLines != len(lines) || gotWidth != width {
+							t.Fatalf("measured %d lines %d wide, want %d lines %d wide with algorithm=%v cut=%v unicode=%v protect=%v", gotLines, gotWidth, len(lines), width, algorithm, cut, unicode, protect)
 						}
 					}
 				}
@@ -183,6 +325,41 @@ func FuzzWrapWithOptions(f *testing.F) {
 	})
 }
 
+func FuzzTruncate(f *testing.F) {
+	f.Add("The quick brown fox jumps", 12, "…")
+	f.Add("/home/user/projects/wrap/main.go", 20, "...")
+	f.Add("日本語のテキストです 🇺🇸🇬🇧 e\u0301", 5, "")
+	f.Add("\x1b[31mred text\x1b[0m here", 6, "[…]")
+	f.Add("see https://example.com/a-b now", 12, "…")
+
+	f.Fuzz(func(t *testing.T, input string, limit int, ellipsis string) {
+		if !utf8.ValidString(input) || !utf8.ValidString(ellipsis) {
+			t.Skip()
+		}
+
+		w := wrap.NewWrapper()
+		w.Ellipsis = ellipsis
+		w.DisplayWidth = true
+		w.EscapeSequences = true
+		for _, mode := range []wrap.TruncateMode{wrap.TruncateEnd, wrap.TruncateStart, wrap.TruncateMiddle} {
+			for _, breakpoints := range []bool{false, true} {
+				w.TruncateMode = mode
+				w.TruncateAtBreakpoints = breakpoints
+				// Protect spans in some modes, so they're cut as well as moved
+				w.Protect.URLs, w.Protect.Paths = mode != wrap.TruncateEnd, mode != wrap.TruncateEnd
+
+				result := w.Truncate(input, limit)
+				if !utf8.ValidString(result) {
+					t.Errorf("result is not valid UTF-8 with mode=%v breakpoints=%v: %q", mode, breakpoints, result)
+				}
+				if limit > 0 && w.StringWidth(result) > limit {
+					t.Errorf("result %q is wider than %d with mode=%v breakpoints=%v", result, limit, mode, breakpoints)
+				}
+			}
+		}
+	})
+}
+
 func FuzzWrapCustomNewline(f *testing.F) {
 	f.Add("hello world", 5, "\n")
 	f.Add("hello world", 5, "\r\n")
@@ -256,3 +433,159 @@ func FuzzWrapTrimOptions(f *testing.F) {
 		}
 	})
 }
+
+func FuzzWrapGraphemes(f *testing.F) {
+	f.Add("🇺🇸🇬🇧🇯🇵", 2)
+	f.Add("👨‍👩‍👧‍👦👨‍👩‍👧‍👦", 1)
+	f.Add("e\u0301e\u0301e\u0301", 1)
+	f.Add("cafe\u0301 cafe\u0301", 4)
+	f.Add("\u1100\u1161\u11a8\u1100\u1161\u11a8", 1)
+	f.Add("👍🏽👍🏽 👍🏽", 1)
+	f.Add("a\u0600 b", 1)
+	f.Add("- \u0301a", 1)
+	f.Add("a\r\nb", 1)
+	f.Add("क्षि क्षि", 2)
+
+	f.Fuzz(func(t *testing.T, input string, limit int) {
+		if !utf8.ValidString(input) {
+			t.Skip()
+		}
+
+		w := wrap.NewWrapper()
+		w.StripTrailingNewline = true
+		for _, displayWidth := range []bool{false, true} {
+			for _, cut := range []bool{false, true} {
+				for _, optimal := range []bool{false, true} {
+					w.DisplayWidth = displayWidth
+					w.CutLongWords = cut
+					w.MinimumRaggedness = optimal
+
+					// Wrap each input line separately so that every line break in the
+					// output was introduced by wrapping
+					for _, inputLine := range strings.Split(input, w.Newline) {
+						lines := strings.Split(w.Wrap(inputLine, limit), w.Newline)
+						for i := 1; i < len(lines); i++ {
+							before, after := wrap.Graphemes(lines[i-1]), wrap.Graphemes(lines[i])
+							if len(before) == 0 || len(after) == 0 {
+								continue
+							}
+							// If a cluster was split, its pieces would rejoin
+							joined := before[len(before)-1] + after[0]
+							if len(wrap.Graphemes(joined)) != 2 {
+								t.Errorf("grapheme cluster split across lines %q and %q with displayWidth=%v cut=%v optimal=%v",
+									lines[i-1], lines[i], displayWidth, cut, optimal)
+							}
+						}
+					}
+				}
+			}
+		}
+	})
+}
+
+func FuzzWrapEscapeSequences(f *testing.F) {
+	f.Add("\x1b[31mhello\x1b[0m world", 5)
+	f.Add("\x1b[1;31;42mabcdefghij\x1b[0m", 3)
+	f.Add("\x1b]8;;http://a-b-c.d\x1b\\link\x1b]8;;\x1b\\ text", 2)
+	f.Add("a\x1b[31m-b\x1b[32m-c", 1)
+	f.Add("\x1b[31", 1)
+	f.Add("\x1b[38;5;196m日本語テスト\x1b[m", 2)
+
+	csi := regexp.MustCompile("\x1b\\[[0-?]*[ -/]*[@-~]")
+
+	f.Fuzz(func(t *testing.T, input string, limit int) {
+		if !utf8.ValidString(input) {
+			t.Skip()
+		}
+
+		w := wrap.NewWrapper()
+		w.EscapeSequences = true
+		for _, cut := range []bool{false, true} {
+			for _, optimal := range []bool{false, true} {
+				for _, reapply := range []bool{false, true} {
+					w.CutLongWords = cut
+					w.MinimumRaggedness = optimal
+					w.ReapplyStyles = reapply
+
+					result := w.Wrap(input, limit)
+					if !utf8.ValidString(result) {
+						t.Errorf("result is not valid UTF-8 with cut=%v optimal=%v reapply=%v: %q", cut, optimal, reapply, result)
+					}
+
+					// Escape sequences must never be broken across lines
+					lines := strings.Split(result, w.Newline)
+					for _, seq := range csi.FindAllString(input, -1) {
+						found := false
+						for _, line := range lines {
+							if strings.Contains(line, seq) {
+								found = true
+								break
+							}
+						}
+						if !found {
+							t.Errorf("escape sequence %q was broken with cut=%v optimal=%v reapply=%v: %q", seq, cut, optimal, reapply, result)
+						}
+					}
+				}
+			}
+		}
+	})
+}
+
+func FuzzWriter(f *testing.F) {
+	f.Add("hello world", 10, 1)
+	f.Add("line1\r\nline2\r\nline3", 5, 3)
+	f.Add("日本語テスト 🇺🇸🇬🇧 é", 3, 2)
+	f.Add("\x1b[31mred text\x1b[0m and more", 6, 4)
+	f.Add(strings.Repeat("a-b ", 100), 7, 5)
+	f.Add("pages 10-20, $(12.50)\u2028日本語。", 4, 2)
+	f.Add("hyphenation of (international) words", 9, 6)
+	f.Add("inter\u00adna\u00adtion\u00adal path/to\u200bfile 10\u00a0km e-\u2060mail", 6, 5)
+	f.Add("\tname\tvalue that wraps\n\t\thyphenation", 9, 3)
+
+	f.Fuzz(func(t *testing.T, input string, limit, size int) {
+		if size < 1 {
+			t.Skip()
+		}
+
+		w := wrap.NewWrapper()
+		w.Newline = "\r\n"
+		w.TrimInputSuffix = " \\"
+		w.EscapeSequences = true
+		w.DisplayWidth = true
+		// Vary MaxLines too, including no maximum
+		w.MaxLines = size % 4
+		if size%2 == 0 {
+			w.Hyphenation.Hyphenator = wrap.HyphenatorEnUS()
+		}
+		if size%3 == 0 {
+			w.Tabs.Width = 4
+		}
+		if size%5 == 0 {
+			w.Measurer = proportional
+		}
+		for _, cut := range []bool{false, true} {
+			for _, unicode := range []bool{false, true} {
+				w.CutLongWords = cut
+				w.UnicodeLineBreaking = unicode
+
+				var sb strings.Builder
+				wr := w.NewWriter(&sb, limit)
+				for s := input; s != ""; {
+					n := size
+					if n > len(s) {
+						n = len(s)
+					}
+					wr.Write([]byte(s[:n]))
+					s = s[n:]
+				}
+				wr.Close()
+
+				// The output must not depend on how the input was split up
+				if expected := w.Wrap(input, limit); sb.String() != expected {
+					t.Errorf("writer output differs from Wrap with cut=%v unicode=%v: got %q, want %q", cut, unicode, sb.String(), expected)
+				}
+			}
+		}
+	})
+}
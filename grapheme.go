@@ -0,0 +1,194 @@
+package wrap
+
+import "unicode/utf8"
+
+// Grapheme_Cluster_Break property values, as defined by UAX #29.
+const (
+	gcbOther = iota
+	gcbCR
+	gcbLF
+	gcbControl
+	gcbExtend
+	gcbZWJ
+	gcbRegionalIndicator
+	gcbPrepend
+	gcbSpacingMark
+	gcbL
+	gcbV
+	gcbT
+	gcbLV
+	gcbLVT
+	gcbExtendedPictographic
+)
+
+// propertyRange assigns a property value to an inclusive range of code points.
+type propertyRange struct {
+	lo, hi rune
+	value  int
+}
+
+// lookupProperty returns the property value assigned to r by the sorted
+// ranges, or def if r isn't covered by any of them.
+func lookupProperty(r rune, ranges []propertyRange, def int) int {
+	lo, hi := 0, len(ranges)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		switch {
+		case r < ranges[mid].lo:
+			hi = mid
+		case r > ranges[mid].hi:
+			lo = mid + 1
+		default:
+			return ranges[mid].value
+		}
+	}
+	return def
+}
+
+// graphemeBreakProperty returns the Grapheme_Cluster_Break property of r.
+func graphemeBreakProperty(r rune) int {
+	// Hangul syllables are either LV or LVT depending on whether they
+	// include a trailing consonant.
+	if r >= 0xAC00 && r <= 0xD7A3 {
+		if (r-0xAC00)%28 == 0 {
+			return gcbLV
+		}
+		return gcbLVT
+	}
+	return lookupProperty(r, graphemeBreakProperties, gcbOther)
+}
+
+// nextGrapheme returns the length in bytes of the extended grapheme cluster
+// at the start of s, following the segmentation rules of UAX #29.
+func nextGrapheme(s string) int {
+	if s == "" {
+		return 0
+	}
+
+	// Fast path: an ASCII character followed by another ASCII character is a
+	// cluster on its own, unless it's part of a CRLF pair.
+	if s[0] < utf8.RuneSelf && (len(s) == 1 || (s[1] < utf8.RuneSelf && (s[0] != '\r' || s[1] != '\n'))) {
+		return 1
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+	prev := graphemeBreakProperty(r)
+	// Number of consecutive regional indicators ending at prev (GB12, GB13)
+	regional := 0
+	if prev == gcbRegionalIndicator {
+		regional = 1
+	}
+	// Whether the text ending at prev matches Extended_Pictographic Extend*,
+	// and Extended_Pictographic Extend* ZWJ respectively (GB11)
+	emoji := prev == gcbExtendedPictographic
+	emojiZWJ := false
+
+	i := size
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		next := graphemeBreakProperty(r)
+		if graphemeBoundary(prev, next, regional, emojiZWJ) {
+			break
+		}
+
+		if next == gcbRegionalIndicator {
+			regional++
+		} else {
+			regional = 0
+		}
+		emojiZWJ = emoji && next == gcbZWJ
+		emoji = next == gcbExtendedPictographic || (emoji && next == gcbExtend)
+
+		prev = next
+		i += size
+	}
+	return i
+}
+
+// graphemeBoundary reports whether there is a grapheme cluster boundary
+// between characters with the properties prev and next. regional is the
+// number of consecutive regional indicators ending at prev, and emojiZWJ
+// reports whether the text ending at prev is an emoji followed by a ZWJ.
+func graphemeBoundary(prev, next, regional int, emojiZWJ bool) bool {
+	switch {
+	case prev == gcbCR && next == gcbLF: // GB3
+		return false
+	case prev == gcbCR, prev == gcbLF, prev == gcbControl: // GB4
+		return true
+	case next == gcbCR, next == gcbLF, next == gcbControl: // GB5
+		return true
+	case prev == gcbL && (next == gcbL || next == gcbV || next == gcbLV || next == gcbLVT): // GB6
+		return false
+	case (prev == gcbLV || prev == gcbV) && (next == gcbV || next == gcbT): // GB7
+		return false
+	case (prev == gcbLVT || prev == gcbT) && next == gcbT: // GB8
+		return false
+	case next == gcbExtend, next == gcbZWJ: // GB9
+		return false
+	case next == gcbSpacingMark: // GB9a
+		return false
+	case prev == gcbPrepend: // GB9b
+		return false
+	case emojiZWJ && next == gcbExtendedPictographic: // GB11
+		return false
+	case prev == gcbRegionalIndicator && next == gcbRegionalIndicator: // GB12, GB13
+		return regional%2 == 0
+	}
+	return true // GB999
+}
+
+// clusterWidth returns the width of a single grapheme cluster as measured by
+// w. Escape sequences have no width. A Measurer measures the cluster if there
+// is one. Otherwise, without DisplayWidth every rune counts, and with it the
+// cluster occupies the width of its first visible character, widened by emoji
+// presentation. Protected spans are as wide as the clusters they're made of.
+func (w *wrapping) clusterWidth(cluster string) int {
+	if w.isEscape(cluster) {
+		return 0
+	}
+	if w.protecting() && len(cluster) > nextGrapheme(cluster) {
+		width := 0
+		for s := cluster; s != ""; {
+			if n := escapeLength(s); w.EscapeSequences && n > 0 {
+				s = s[n:]
+				continue
+			}
+			n := nextGrapheme(s)
+			width += w.graphemeWidth(s[:n])
+			s = s[n:]
+		}
+		return width
+	}
+	return w.graphemeWidth(cluster)
+}
+
+// graphemeWidth returns the width of a single grapheme cluster that isn't an
+// escape sequence.
+func (w *wrapping) graphemeWidth(cluster string) int {
+	if w.Measurer != nil {
+		// Soft hyphens and other invisible characters are never measured
+		if r, _ := utf8.DecodeRuneInString(cluster); isInvisible(r) {
+			return 0
+		}
+		return w.Measurer.Width(cluster)
+	}
+	if !w.DisplayWidth {
+		return runeWidth(cluster)
+	}
+	width := 0
+	for _, r := range cluster {
+		if width == 0 {
+			width = displayWidth(r, w.AmbiguousWidth)
+			if r >= 0x1F1E6 && r <= 0x1F1FF {
+				// Regional indicator pairs render as a single wide flag
+				width = 2
+			}
+			continue
+		}
+		if r == 0xFE0F && width == 1 {
+			// Variation selector 16 requests emoji presentation
+			width = 2
+		}
+	}
+	return width
+}
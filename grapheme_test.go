@@ -0,0 +1,101 @@
+package wrap
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// readBreakTest parses a Unicode break test file from testdata, returning
+// each test case as the expected segments.
+func readBreakTest(t *testing.T, name string) [][]string {
+	t.Helper()
+
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var cases [][]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		var segments []string
+		var segment strings.Builder
+		for _, field := range strings.Fields(line) {
+			switch field {
+			case "÷":
+				if segment.Len() > 0 {
+					segments = append(segments, segment.String())
+					segment.Reset()
+				}
+			case "×":
+			default:
+				r, err := strconv.ParseUint(field, 16, 32)
+				if err != nil {
+					t.Fatalf("invalid code point %q: %v", field, err)
+				}
+				segment.WriteRune(rune(r))
+			}
+		}
+		cases = append(cases, segments)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return cases
+}
+
+func TestNextGrapheme_Conformance(t *testing.T) {
+	for _, expected := range readBreakTest(t, "GraphemeBreakTest.txt") {
+		input := strings.Join(expected, "")
+
+		var got []string
+		for s := input; s != ""; {
+			n := nextGrapheme(s)
+			got = append(got, s[:n])
+			s = s[n:]
+		}
+
+		if strings.Join(got, "÷") != strings.Join(expected, "÷") {
+			t.Errorf("segmenting %+q: got %+q, want %+q", input, got, expected)
+		}
+	}
+}
+
+func TestWrapper_ClusterWidth(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		displayWidth bool
+		expected     int
+	}{
+		{"runes", "e\u0301", false, 2},
+		{"combining mark", "e\u0301", true, 1},
+		{"flag", "🇬🇧", true, 2},
+		{"family", "👨‍👩‍👧", true, 2},
+		{"emoji presentation selector", "☺️", true, 2},
+		{"hangul jamo", "\u1100\u1161\u11a8", true, 2},
+		{"wide", "日", true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrapping{Wrapper: NewWrapper()}
+			w.DisplayWidth = tt.displayWidth
+			if got := w.clusterWidth(tt.input); got != tt.expected {
+				t.Errorf("clusterWidth(%q) = %d, want %d", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
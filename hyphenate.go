@@ -0,0 +1,344 @@
+package wrap
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed patterns/hyph-en-us.tex
+var enUSPatterns string
+
+var (
+	enUSOnce       sync.Once
+	enUSHyphenator *Hyphenator
+)
+
+// HyphenatorEnUS returns a Hyphenator for American English, using the
+// patterns devised by Frank M. Liang for TeX.
+func HyphenatorEnUS() *Hyphenator {
+	enUSOnce.Do(func() {
+		h, err := NewHyphenator(strings.NewReader(enUSPatterns))
+		if err != nil {
+			panic(err)
+		}
+		enUSHyphenator = h
+	})
+	return enUSHyphenator
+}
+
+// HyphenationOptions configures where words may be hyphenated.
+type HyphenationOptions struct {
+	// Hyphenator finds the hyphenation points in each word. Words are only
+	// hyphenated at soft hyphens if it's nil.
+	// Default: nil
+	Hyphenator *Hyphenator
+
+	// MinLeft and MinRight are the fewest characters of a word that can be
+	// left before and after a hyphenation point.
+	// Default: 2 and 3, as used by TeX for English
+	MinLeft, MinRight int
+
+	// Hyphen is written at the end of a line broken at a hyphenation point,
+	// and counts towards the limit. It's "-" if empty.
+	// Default: "-"
+	Hyphen string
+
+	// Penalty is added to the cost of a line ending at a hyphenation point by
+	// AlgorithmMinimumRaggedness and AlgorithmBalanced, in the same units as
+	// WidowOptions, so that they only hyphenate words when it helps enough.
+	// AlgorithmKnuthPlass uses KnuthPlass.HyphenPenalty instead.
+	// Default: 0
+	Penalty float64
+}
+
+const (
+	defaultHyphen         = "-"
+	defaultHyphenMinLeft  = 2
+	defaultHyphenMinRight = 3
+
+	// softHyphen marks a place a word may be hyphenated, and is invisible
+	// unless the word is broken there.
+	softHyphen = "\u00AD"
+)
+
+// Hyphenator finds the places a word may be hyphenated, using Liang's
+// algorithm with a set of patterns in the format used by TeX.
+type Hyphenator struct {
+	// patterns maps the letters of each pattern to its values, which are
+	// the priorities of breaking before each letter, and after the last.
+	patterns map[string][]byte
+	// exceptions maps words to the indexes of the runes they may be
+	// hyphenated before.
+	exceptions map[string][]int
+	// maxLength is the number of runes in the longest pattern.
+	maxLength int
+}
+
+// NewHyphenator reads hyphenation patterns and exceptions in the format used
+// by TeX, such as the files of the hyph-utf8 project, and returns a
+// Hyphenator that uses them. Patterns are read from \patterns{...} and
+// exceptions from \hyphenation{...}, while anything after % on a line is a
+// comment. A file containing only patterns, separated by whitespace, is also
+// accepted. Patterns must be written in UTF-8, as the ^^ notation of TeX
+// isn't supported.
+func NewHyphenator(r io.Reader) (*Hyphenator, error) {
+	h := &Hyphenator{
+		patterns:   make(map[string][]byte),
+		exceptions: make(map[string][]int),
+	}
+
+	sc := bufio.NewScanner(r)
+	var block string
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		if i := strings.IndexByte(text, '%'); i >= 0 {
+			text = text[:i]
+		}
+		for _, field := range strings.Fields(text) {
+			for field != "" {
+				if block == "" && strings.HasPrefix(field, `\`) {
+					i := strings.IndexByte(field, '{')
+					if i < 0 {
+						return nil, fmt.Errorf("wrap: line %d: expected { after %s", line, field)
+					}
+					switch field[:i] {
+					case `\patterns`, `\hyphenation`:
+						block = field[1:i]
+					default:
+						return nil, fmt.Errorf("wrap: line %d: unsupported command %s", line, field[:i])
+					}
+					field = field[i+1:]
+					continue
+				}
+
+				word := field
+				end := strings.IndexByte(field, '}')
+				if end >= 0 {
+					word, field = field[:end], field[end+1:]
+				} else {
+					field = ""
+				}
+				var err error
+				switch {
+				case word == "":
+				case block == "hyphenation":
+					h.addException(word)
+				default:
+					err = h.addPattern(word)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("wrap: line %d: %w", line, err)
+				}
+				if end >= 0 {
+					if block == "" {
+						return nil, fmt.Errorf("wrap: line %d: unexpected }", line)
+					}
+					block = ""
+				}
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if block != "" {
+		return nil, fmt.Errorf(`wrap: missing } after \%s`, block)
+	}
+	return h, nil
+}
+
+// addPattern adds a pattern such as "a1b", where each digit is the priority
+// of breaking between the letters either side of it. "." matches the start
+// or end of a word.
+func (h *Hyphenator) addPattern(pattern string) error {
+	var letters strings.Builder
+	values := []byte{0}
+	digit := false
+	for _, r := range pattern {
+		if r >= '0' && r <= '9' {
+			if digit {
+				return fmt.Errorf("invalid hyphenation pattern %q", pattern)
+			}
+			values[len(values)-1] = byte(r - '0')
+			digit = true
+			continue
+		}
+		letters.WriteRune(unicode.ToLower(r))
+		values = append(values, 0)
+		digit = false
+	}
+	if letters.Len() == 0 {
+		return fmt.Errorf("invalid hyphenation pattern %q", pattern)
+	}
+	h.patterns[letters.String()] = values
+	if n := len(values) - 1; n > h.maxLength {
+		h.maxLength = n
+	}
+	return nil
+}
+
+// addException adds a word written with a hyphen at each of the places it
+// may be hyphenated, such as "ta-ble".
+func (h *Hyphenator) addException(word string) {
+	var letters strings.Builder
+	var points []int
+	n := 0
+	for _, r := range word {
+		if r == '-' {
+			points = append(points, n)
+			continue
+		}
+		letters.WriteRune(unicode.ToLower(r))
+		n++
+	}
+	h.exceptions[letters.String()] = points
+}
+
+// Hyphenate returns the byte indexes in word at which it may be hyphenated,
+// leaving at least minLeft runes before each and minRight runes after. The
+// word is matched against the patterns ignoring case, and should consist of
+// letters only.
+func (h *Hyphenator) Hyphenate(word string, minLeft, minRight int) []int {
+	if minLeft < 1 {
+		minLeft = 1
+	}
+	if minRight < 1 {
+		minRight = 1
+	}
+	count := utf8.RuneCountInString(word)
+	if h == nil || count < minLeft+minRight {
+		return nil
+	}
+
+	// offsets holds the byte index of each rune in word, and one past the end
+	offsets := make([]int, 0, count+1)
+	var sb strings.Builder
+	sb.WriteByte('.')
+	for i, r := range word {
+		offsets = append(offsets, i)
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	offsets = append(offsets, len(word))
+	sb.WriteByte('.')
+	padded := sb.String()
+
+	var points []int
+	if exception, ok := h.exceptions[padded[1:len(padded)-1]]; ok {
+		for _, n := range exception {
+			if n >= minLeft && n <= count-minRight {
+				points = append(points, offsets[n])
+			}
+		}
+		return points
+	}
+
+	// starts holds the byte index of each rune in padded, and one past the end
+	starts := make([]int, 0, count+3)
+	for i := range padded {
+		starts = append(starts, i)
+	}
+	starts = append(starts, len(padded))
+
+	// values[i] is the highest priority of breaking before rune i of padded,
+	// where odd priorities allow a break and even ones prevent it.
+	values := make([]byte, len(starts))
+	for i := 0; i < len(starts)-1; i++ {
+		for j := i + 1; j < len(starts) && j-i <= h.maxLength; j++ {
+			pattern, ok := h.patterns[padded[starts[i]:starts[j]]]
+			if !ok {
+				continue
+			}
+			for k, v := range pattern {
+				if v > values[i+k] {
+					values[i+k] = v
+				}
+			}
+		}
+	}
+
+	// Rune n of word is rune n+1 of padded
+	for n := minLeft; n <= count-minRight; n++ {
+		if values[n+1]%2 == 1 {
+			points = append(points, offsets[n])
+		}
+	}
+	return points
+}
+
+// hyphenating reports whether words in s may be hyphenated, which is the case
+// if there's a Hyphenator or s contains a soft hyphen.
+func (w *wrapping) hyphenating(s string) bool {
+	return w.Hyphenation.Hyphenator != nil || strings.Contains(s, softHyphen)
+}
+
+// hyphenPoints returns the byte indexes at which s may be hyphenated. If s
+// contains soft hyphens, these are the indexes of the soft hyphens, which are
+// dropped when the word is broken at them. Otherwise each run of letters in s
+// is hyphenated separately by the Hyphenator, so punctuation around a word
+// and escape sequences within it are left alone, and grapheme clusters are
+// never split.
+func (w *wrapping) hyphenPoints(s string) []int {
+	if points := w.softHyphens(s); points != nil {
+		return points
+	}
+	if w.Hyphenation.Hyphenator == nil {
+		return nil
+	}
+
+	var points, boundaries []int
+	start := -1
+	for i := 0; i <= len(s); {
+		n := 0
+		letter := false
+		if i < len(s) {
+			n = w.nextCluster(s[i:])
+			r, _ := utf8.DecodeRuneInString(s[i:])
+			letter = unicode.IsLetter(r) && !w.isEscape(s[i:i+n]) && w.protectedLength(s[i:i+n]) == 0
+		}
+		switch {
+		case letter && start < 0:
+			start, boundaries = i, boundaries[:0]
+		case letter:
+			boundaries = append(boundaries, i)
+		case start >= 0:
+			k := 0
+			for _, p := range w.Hyphenation.Hyphenator.Hyphenate(s[start:i], w.Hyphenation.MinLeft, w.Hyphenation.MinRight) {
+				for k < len(boundaries) && boundaries[k] < start+p {
+					k++
+				}
+				if k < len(boundaries) && boundaries[k] == start+p {
+					points = append(points, start+p)
+				}
+			}
+			start = -1
+		}
+		if i == len(s) {
+			break
+		}
+		i += n
+	}
+	return points
+}
+
+// softHyphens returns the indexes of the soft hyphens in s that have text on
+// both sides, or nil if there aren't any.
+func (w *wrapping) softHyphens(s string) []int {
+	if !strings.Contains(s, softHyphen) {
+		return nil
+	}
+	var points []int
+	for i := 0; i < len(s); {
+		n := w.nextCluster(s[i:])
+		if s[i:i+n] == softHyphen && i > 0 && i+n < len(s) {
+			points = append(points, i)
+		}
+		i += n
+	}
+	return points
+}
@@ -0,0 +1,120 @@
+package wrap
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHyphenatorEnUS(t *testing.T) {
+	tests := []struct {
+		word     string
+		expected string
+	}{
+		{"hyphenation", "hy-phen-a-tion"},
+		{"computer", "com-puter"},
+		{"algorithm", "al-go-rithm"},
+		{"Paragraph", "Para-graph"},
+		{"international", "in-ter-na-tional"},
+		{"academy", "acad-emy"},
+		{"project", "project"},
+		{"a", "a"},
+		{"", ""},
+	}
+
+	h := HyphenatorEnUS()
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if got := hyphenated(tt.word, h.Hyphenate(tt.word, 2, 3)); got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewHyphenator(t *testing.T) {
+	const patterns = `% A comment \patterns{ignored}
+\patterns{ 1ba .a2b
+c1c }
+\hyphenation{Ab-ab-ab}`
+
+	h, err := NewHyphenator(strings.NewReader(patterns))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		word        string
+		left, right int
+		expected    string
+	}{
+		{"abababab", 1, 1, "aba-ba-bab"},
+		{"abababab", 4, 4, "abababab"},
+		{"ABABABAB", 1, 1, "ABA-BA-BAB"},
+		{"ababab", 1, 1, "ab-ab-ab"},
+		{"ababab", 3, 1, "abab-ab"},
+		{"accb", 1, 1, "ac-cb"},
+		{"ábab", 1, 1, "á-bab"},
+	}
+	for _, tt := range tests {
+		if got := hyphenated(tt.word, h.Hyphenate(tt.word, tt.left, tt.right)); got != tt.expected {
+			t.Errorf("%q with %d, %d: got %q, want %q", tt.word, tt.left, tt.right, got, tt.expected)
+		}
+	}
+
+	// A file of patterns alone is accepted too
+	h, err = NewHyphenator(strings.NewReader("1ba\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Hyphenate("abab", 1, 1); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("got %v, want [1]", got)
+	}
+
+	for _, invalid := range []string{
+		`\patterns{a12b}`,
+		`\patterns{a`,
+		`\message{hello}`,
+		`\patterns`,
+		`a}`,
+		`\patterns{3}`,
+	} {
+		if _, err := NewHyphenator(strings.NewReader(invalid)); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
+
+func TestWrapper_HyphenPoints(t *testing.T) {
+	w := wrapping{Wrapper: NewWrapper()}
+	w.Hyphenation.Hyphenator = HyphenatorEnUS()
+	w.EscapeSequences = true
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"(hyphenation),", "(hy-phen-a-tion),"},
+		{"computer-algorithm", "com-puter-al-go-rithm"},
+		{"\x1b[31mcomputer\x1b[0m", "\x1b[31mcom-puter\x1b[0m"},
+		{"computér", "com-putér"},
+		{"123456789", "123456789"},
+	}
+	for _, tt := range tests {
+		if got := hyphenated(tt.input, w.hyphenPoints(tt.input)); got != tt.expected {
+			t.Errorf("got %q, want %q", got, tt.expected)
+		}
+	}
+}
+
+// hyphenated returns s with a hyphen at each of the points.
+func hyphenated(s string, points []int) string {
+	var sb strings.Builder
+	prev := 0
+	for _, p := range points {
+		sb.WriteString(s[prev:p])
+		sb.WriteByte('-')
+		prev = p
+	}
+	sb.WriteString(s[prev:])
+	return sb.String()
+}
@@ -0,0 +1,98 @@
+package wrap
+
+import "strings"
+
+// indentSpace contains the characters that can indent a line.
+const indentSpace = " \t"
+
+// splitIndent returns the length in bytes of the indent and list markers at
+// the start of s, including any spaces after the markers, and the length of
+// just the leading indent. Markers are only recognised when followed by a
+// space and more text.
+func splitIndent(s string) (prefix, indent int) {
+	indent = len(s) - len(strings.TrimLeft(s, indentSpace))
+	prefix = indent
+	for {
+		n := listMarkerLength(s[prefix:])
+		if n == 0 {
+			return prefix, indent
+		}
+		rest := strings.TrimLeft(s[prefix+n:], indentSpace)
+		if len(rest) == len(s[prefix+n:]) || rest == "" {
+			return prefix, indent
+		}
+		prefix = len(s) - len(rest)
+	}
+}
+
+// startsListItem reports whether s, the text after a break, would be taken
+// for list or quote markers at the start of a line once its indent is
+// trimmed, so that a line wrapped with PreserveIndent can't be broken there.
+func startsListItem(s string) bool {
+	prefix, _ := splitIndent(strings.TrimLeft(s, indentSpace))
+	return prefix > 0
+}
+
+// listMarkerLength returns the length of the list or quote marker at the
+// start of s: one of "-", "*", "+", "•" or ">", or a number of up to nine
+// digits followed by "." or ")". It returns 0 if s doesn't start with one.
+func listMarkerLength(s string) int {
+	for _, marker := range []string{"-", "*", "+", "•", ">"} {
+		if strings.HasPrefix(s, marker) {
+			return len(marker)
+		}
+	}
+
+	digits := 0
+	for digits < len(s) && digits < 9 && s[digits] >= '0' && s[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 && digits < len(s) && (s[digits] == '.' || s[digits] == ')') {
+		return digits + 1
+	}
+	return 0
+}
+
+// indentDecided reports whether more text appended to s can't change what
+// withIndent does with it, as the first word after the indent and markers has
+// ended and been followed by more text.
+func (w *wrapping) indentDecided(s string) bool {
+	prefix, _ := splitIndent(s)
+	rest := s[prefix:]
+	i := strings.IndexAny(rest, indentSpace)
+	return i >= 0 && strings.TrimLeft(rest[i:], indentSpace) != "" && w.trimBreakpoints(rest) != ""
+}
+
+// withIndent returns a copy of w that writes the indent and markers at the
+// start of the input line s as part of its first line prefix, and lines up
+// its continuation lines with the text after them, along with the rest of s.
+// Lines without any text after the indent are returned unchanged.
+func (w *wrapping) withIndent(s string) (wrapping, string) {
+	iw := *w
+	prefix, indent := splitIndent(s)
+	if prefix == 0 || w.trimBreakpoints(s[prefix:]) == "" {
+		return iw, s
+	}
+	iw.FirstLinePrefix += s[:prefix]
+	iw.ContinuationPrefix += s[:indent] + w.continuationIndent(s[indent:prefix])
+	return iw, s[prefix:]
+}
+
+// continuationIndent returns what continuation lines start with in place of
+// markers, the list and quote markers at the start of an input line and the
+// spaces after them. Quote markers are repeated, as the lines are still part
+// of the quote, and list markers are replaced by spaces.
+func (w *wrapping) continuationIndent(markers string) string {
+	var sb strings.Builder
+	for i := 0; i < len(markers); {
+		n := listMarkerLength(markers[i:])
+		end := len(markers) - len(strings.TrimLeft(markers[i+n:], indentSpace))
+		if markers[i] == '>' {
+			sb.WriteString(markers[i:end])
+		} else {
+			sb.WriteString(w.spaces(w.stringWidth(markers[:end]) - w.stringWidth(markers[:i])))
+		}
+		i = end
+	}
+	return sb.String()
+}
@@ -0,0 +1,94 @@
+package wrap
+
+import "testing"
+
+func TestSplitIndent(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		prefix int
+		indent int
+	}{
+		{"no indent", "text", 0, 0},
+		{"spaces", "    text", 4, 4},
+		{"tab", "\ttext", 1, 1},
+		{"dash", "- item", 2, 0},
+		{"indented star", "  * item", 4, 2},
+		{"plus with spaces", "+   item", 4, 0},
+		{"bullet", "• item", 4, 0},
+		{"quote", "> quoted", 2, 0},
+		{"nested", "> - item", 4, 0},
+		{"number", "12. item", 4, 0},
+		{"number with paren", "3) item", 3, 0},
+		{"number without punctuation", "12 items", 0, 0},
+		{"marker without space", "-item", 0, 0},
+		{"marker without text", "  -", 2, 2},
+		{"marker with only spaces", "-   ", 0, 0},
+		{"negative number", "-1 point", 0, 0},
+		{"too many digits", "1234567890. item", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix, indent := splitIndent(tt.input)
+			if prefix != tt.prefix || indent != tt.indent {
+				t.Errorf("splitIndent(%q) = %d, %d, want %d, %d", tt.input, prefix, indent, tt.prefix, tt.indent)
+			}
+		})
+	}
+}
+
+func TestContinuationIndent(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"- ", "  "},
+		{"12.  ", "     "},
+		{"> ", "> "},
+		{"> > ", "> > "},
+		{">\t", ">\t"},
+		{"> - ", ">   "},
+		{"- > ", "  > "},
+		{"• ", "  "},
+	}
+
+	for _, tt := range tests {
+		w := newWrapping(NewWrapper())
+		if got := w.continuationIndent(tt.input); got != tt.expected {
+			t.Errorf("continuationIndent(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestIndentDecided(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"", false},
+		{"  ", false},
+		{"  1", false},
+		{"  12.", false},
+		{"  12. ", false},
+		{"  12. item", false},
+		{"  12. item ", false},
+		{"  12. item t", true},
+		{"- > ", false},
+		{"- > x", false},
+		{"- > x y", true},
+		{"word", false},
+		{"word word", true},
+		{"-- -", false},
+		{"-- -x", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			w := wrapping{Wrapper: NewWrapper()}
+			if got := w.indentDecided(tt.input); got != tt.expected {
+				t.Errorf("indentDecided(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,368 @@
+// Command gen generates tables.go, the Unicode property tables used to
+// measure and break text, from the Unicode Character Database.
+//
+// Usage:
+//
+//	go run ./internal/gen [-version 15.0.0] [-ucd dir] [-o tables.go]
+//
+// The data files are downloaded from unicode.org for the given version,
+// unless -ucd names a directory holding a copy of the database laid out as
+// it's published, with EastAsianWidth.txt, LineBreak.txt,
+// auxiliary/GraphemeBreakProperty.txt, emoji/emoji-data.txt and
+// extracted/DerivedGeneralCategory.txt. It's run by go generate in the root
+// of the module.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxRune is the last code point.
+const maxRune = 0x10FFFF
+
+func main() {
+	version := flag.String("version", "15.0.0", "Unicode `version` to download")
+	ucd := flag.String("ucd", "", "read the database from `dir` instead of downloading it")
+	out := flag.String("o", "tables.go", "write the tables to `file`")
+	flag.Parse()
+
+	src, err := generate(source{version: *version, dir: *ucd})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gen:", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "gen:", err)
+		os.Exit(1)
+	}
+}
+
+// source reads the data files of one version of the database, either from
+// unicode.org or from a local directory.
+type source struct {
+	version string
+	dir     string
+}
+
+// open returns the contents of the data file at the given path within the
+// database.
+func (s source) open(path string) (io.ReadCloser, error) {
+	if s.dir != "" {
+		return os.Open(filepath.Join(s.dir, filepath.FromSlash(path)))
+	}
+	url := "https://www.unicode.org/Public/" + s.version + "/ucd/" + path
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// property holds the value of a property for every code point.
+type property []string
+
+// entry is a line of a data file, assigning a value to a range of code
+// points.
+type entry struct {
+	lo, hi rune
+	value  string
+}
+
+// load reads a property from a data file, where each line assigns the value
+// in its second field to the code point or range of code points in its first.
+// Code points that aren't listed take the value of any @missing line that
+// covers them, or def if there isn't one. If any values are given, lines
+// with other values are ignored, for files such as emoji-data.txt that list
+// a code point once for each property it has.
+func (s source) load(path, def string, values ...string) (property, error) {
+	f, err := s.open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var missing, listed []entry
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		isMissing := strings.HasPrefix(line, "# @missing:")
+		if isMissing {
+			line = strings.TrimPrefix(line, "# @missing:")
+		} else if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.Split(line, ";")
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: missing value", path, n)
+		}
+		lo, hi, err := parseRange(strings.TrimSpace(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		e := entry{lo, hi, strings.TrimSpace(fields[1])}
+		if len(values) > 0 && !contains(values, e.value) {
+			continue
+		}
+		if isMissing {
+			missing = append(missing, e)
+		} else {
+			listed = append(listed, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	p := make(property, maxRune+1)
+	for r := range p {
+		p[r] = def
+	}
+	// Values on @missing lines are only defaults, so they're applied before
+	// any of the values that are listed, wherever they are in the file.
+	for _, e := range append(missing, listed...) {
+		for r := e.lo; r <= e.hi; r++ {
+			p[r] = e.value
+		}
+	}
+	return p, nil
+}
+
+// contains reports whether values contains v.
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRange parses a code point such as "0041", or a range of them such as
+// "0041..005A".
+func parseRange(s string) (rune, rune, error) {
+	first, last := s, s
+	if i := strings.Index(s, ".."); i >= 0 {
+		first, last = s[:i], s[i+2:]
+	}
+	lo, err := strconv.ParseUint(first, 16, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad code point %q", first)
+	}
+	hi, err := strconv.ParseUint(last, 16, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad code point %q", last)
+	}
+	if lo > hi || hi > maxRune {
+		return 0, 0, fmt.Errorf("bad range %q", s)
+	}
+	return rune(lo), rune(hi), nil
+}
+
+// Names of the constants in the wrap package for each property value.
+var (
+	graphemeBreakNames = map[string]string{
+		"CR":                    "gcbCR",
+		"LF":                    "gcbLF",
+		"Control":               "gcbControl",
+		"Extend":                "gcbExtend",
+		"ZWJ":                   "gcbZWJ",
+		"Regional_Indicator":    "gcbRegionalIndicator",
+		"Prepend":               "gcbPrepend",
+		"SpacingMark":           "gcbSpacingMark",
+		"L":                     "gcbL",
+		"V":                     "gcbV",
+		"T":                     "gcbT",
+		"Extended_Pictographic": "gcbExtendedPictographic",
+	}
+	lineBreakNames = map[string]bool{
+		"B2": true, "BA": true, "BB": true, "BK": true, "CB": true, "CL": true,
+		"CM": true, "CP": true, "CR": true, "EB": true, "EM": true, "EX": true,
+		"GL": true, "H2": true, "H3": true, "HL": true, "HY": true, "ID": true,
+		"IN": true, "IS": true, "JL": true, "JT": true, "JV": true, "LF": true,
+		"NL": true, "NS": true, "NU": true, "OP": true, "PO": true, "PR": true,
+		"QU": true, "RI": true, "SP": true, "SY": true, "WJ": true, "ZW": true,
+		"ZWJ": true,
+	}
+)
+
+// generate returns the formatted source of tables.go.
+func generate(s source) ([]byte, error) {
+	eaw, err := s.load("EastAsianWidth.txt", "N")
+	if err != nil {
+		return nil, err
+	}
+	gc, err := s.load("extracted/DerivedGeneralCategory.txt", "Cn")
+	if err != nil {
+		return nil, err
+	}
+	gcb, err := s.load("auxiliary/GraphemeBreakProperty.txt", "Other")
+	if err != nil {
+		return nil, err
+	}
+	pictographic, err := s.load("emoji/emoji-data.txt", "", "Extended_Pictographic")
+	if err != nil {
+		return nil, err
+	}
+	lb, err := s.load("LineBreak.txt", "XX")
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `// Code generated by go run ./internal/gen; DO NOT EDIT.
+
+//go:generate go run ./internal/gen -version %s
+
+package wrap
+
+// The tables in this file are derived from the Unicode Character Database,
+// version %s. See https://www.unicode.org/license.html for the Unicode
+// license agreement.
+`, s.version, s.version)
+
+	writeRanges(&b, `eastAsianWide contains the code points with an East_Asian_Width property of
+Wide (W) or Fullwidth (F), from EastAsianWidth.txt.`, "eastAsianWide", func(r rune) bool {
+		return eaw[r] == "W" || eaw[r] == "F"
+	})
+
+	writeRanges(&b, `eastAsianAmbiguous contains the code points with an East_Asian_Width
+property of Ambiguous (A), from EastAsianWidth.txt.`, "eastAsianAmbiguous", func(r rune) bool {
+		return eaw[r] == "A"
+	})
+
+	// Hangul syllables are left out, and Extended_Pictographic code points
+	// are otherwise Other.
+	err = writeProperties(&b, `graphemeBreakProperties contains the Grapheme_Cluster_Break property values
+from GraphemeBreakProperty.txt, other than Hangul syllables which are
+derived arithmetically, along with Extended_Pictographic from
+emoji-data.txt.`, "graphemeBreakProperties", func(r rune) (string, error) {
+		v := gcb[r]
+		switch {
+		case v == "LV" || v == "LVT":
+			return "", nil
+		case v == "Other":
+			if pictographic[r] == "" {
+				return "", nil
+			}
+			v = pictographic[r]
+		}
+		name, ok := graphemeBreakNames[v]
+		if !ok {
+			return "", fmt.Errorf("U+%04X: unknown Grapheme_Cluster_Break %q", r, v)
+		}
+		return name, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeProperties(&b, `lineBreakClasses contains the Line_Break property of code points, from
+LineBreak.txt, resolved as described by rule LB1 of UAX #14. Code points
+that are not listed are alphabetic (AL).`, "lineBreakClasses", func(r rune) (string, error) {
+		v := lb[r]
+		switch v {
+		case "AI", "SG", "XX", "AL":
+			return "", nil
+		case "SA":
+			if gc[r] != "Mn" && gc[r] != "Mc" {
+				return "", nil
+			}
+			v = "CM"
+		case "CJ":
+			v = "NS"
+		}
+		if !lineBreakNames[v] {
+			return "", fmt.Errorf("U+%04X: unknown Line_Break %q", r, v)
+		}
+		return "lb" + v, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	writeRanges(&b, `eastAsianBrackets contains the opening and closing punctuation with an
+East_Asian_Width property of Fullwidth (F), Wide (W) or Halfwidth (H),
+which are excluded from rule LB30 of UAX #14.`, "eastAsianBrackets", func(r rune) bool {
+		return (lb[r] == "OP" || lb[r] == "CP") && (eaw[r] == "F" || eaw[r] == "W" || eaw[r] == "H")
+	})
+
+	writeRanges(&b, `unassignedPictographic contains the unassigned code points reserved for
+future emoji, which are Extended_Pictographic in emoji-data.txt.`, "unassignedPictographic", func(r rune) bool {
+		return pictographic[r] != "" && gc[r] == "Cn"
+	})
+
+	return format.Source(b.Bytes())
+}
+
+// writeComment writes text as a doc comment.
+func writeComment(b *bytes.Buffer, text string) {
+	b.WriteString("\n")
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString("// " + line + "\n")
+	}
+}
+
+// writeRanges writes a []runeRange named name, holding the ranges of code
+// points for which in returns true.
+func writeRanges(b *bytes.Buffer, doc, name string, in func(r rune) bool) {
+	writeComment(b, doc)
+	fmt.Fprintf(b, "var %s = []runeRange{\n", name)
+	for r := rune(0); r <= maxRune; r++ {
+		if !in(r) {
+			continue
+		}
+		lo := r
+		for r < maxRune && in(r+1) {
+			r++
+		}
+		fmt.Fprintf(b, "\t{0x%04X, 0x%04X},\n", lo, r)
+	}
+	b.WriteString("}\n")
+}
+
+// writeProperties writes a []propertyRange named name, holding the ranges of
+// code points with the same value returned by value, which is the name of
+// a constant, leaving out those for which it returns "".
+func writeProperties(b *bytes.Buffer, doc, name string, value func(r rune) (string, error)) error {
+	writeComment(b, doc)
+	fmt.Fprintf(b, "var %s = []propertyRange{\n", name)
+	values := make([]string, maxRune+1)
+	for r := range values {
+		v, err := value(rune(r))
+		if err != nil {
+			return err
+		}
+		values[r] = v
+	}
+	for r := rune(0); r <= maxRune; r++ {
+		v := values[r]
+		if v == "" {
+			continue
+		}
+		lo := r
+		for r < maxRune && values[r+1] == v {
+			r++
+		}
+		fmt.Fprintf(b, "\t{0x%04X, 0x%04X, %s},\n", lo, r, v)
+	}
+	b.WriteString("}\n")
+	return nil
+}
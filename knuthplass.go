@@ -0,0 +1,389 @@
+package wrap
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// KnuthPlassOptions tunes the Knuth–Plass line breaking algorithm. Badness
+// and demerits are measured as in TeX, where a line with a badness of 100 has
+// its spaces stretched or shrunk by their full amount.
+type KnuthPlassOptions struct {
+	// Tolerance is the largest badness allowed for a line. If no set of
+	// breaks stays within it, lines of any badness are allowed instead.
+	// Default: 200
+	Tolerance float64
+
+	// SpaceStretch is how far each space may stretch, as a fraction of its
+	// width.
+	// Default: 0.5
+	SpaceStretch float64
+
+	// SpaceShrink is how far each space may shrink, as a fraction of its
+	// width. Spaces aren't shrunk in the output, so lines may exceed the limit
+	// by up to this amount.
+	// Default: 0
+	SpaceShrink float64
+
+	// LinePenalty is added to the badness of every line, so that fewer lines
+	// are preferred.
+	// Default: 10
+	LinePenalty float64
+
+	// HyphenPenalty is the penalty for breaking a line after a hyphen.
+	// Default: 50
+	HyphenPenalty float64
+
+	// FlaggedDemerits are added when two consecutive lines end with hyphens.
+	// Default: 10000
+	FlaggedDemerits float64
+
+	// FitnessDemerits are added when two consecutive lines are spaced very
+	// differently, such as a tight line followed by a loose one.
+	// Default: 10000
+	FitnessDemerits float64
+}
+
+// defaultKnuthPlass contains the default options, which follow plain TeX.
+var defaultKnuthPlass = KnuthPlassOptions{
+	Tolerance:       200,
+	SpaceStretch:    0.5,
+	LinePenalty:     10,
+	HyphenPenalty:   50,
+	FlaggedDemerits: 10000,
+	FitnessDemerits: 10000,
+}
+
+const (
+	// maxBadness is the badness of a line that can't be adjusted to fit.
+	maxBadness = 10000
+	// overfullDemerits are added for each column a line exceeds the limit by,
+	// so the least overfull line is chosen when nothing fits.
+	overfullDemerits = 1e12
+)
+
+// Kinds of item in the Knuth–Plass paragraph model.
+const (
+	itemBox = iota
+	itemGlue
+	itemPenalty
+)
+
+// kpItem is a box, glue or penalty in the Knuth–Plass paragraph model. Boxes
+// are unbreakable text, glue is breakable space which can stretch and shrink,
+// and penalties are other places a line may be broken at some cost.
+type kpItem struct {
+	kind    int
+	width   int
+	stretch float64
+	shrink  float64
+	penalty float64
+	flagged bool
+	// text is the text of a box or glue, or the text written at the end of a
+	// line that breaks at a penalty.
+	text string
+}
+
+// kpNode is an active breakpoint: a way to break the paragraph up to an item.
+type kpNode struct {
+	// position is the index of the item broken at, and start is the index of
+	// the first item of the following line.
+	position, start int
+	fitness         int
+	flagged         bool
+	demerits        float64
+	prev            *kpNode
+}
+
+// wrapKnuthPlassLines wraps text using the Knuth–Plass total-fit algorithm,
+// where the first line may have a different limit to the rest, returning a
+// slice of lines.
+func (w *wrapping) wrapKnuthPlassLines(s string, first, limit int) []string {
+	words := w.hyphenateWords(w.splitWordsWithSep(s))
+	if len(words) == 0 {
+		return []string{""}
+	}
+	if w.CutLongWords {
+		words = w.cutLongWordsInListWithSep(words, first, limit)
+	}
+
+	items := w.knuthPlassItems(words)
+	// Every line starting with the first box is the first line, so widen or
+	// narrow that box to make up the difference in limits.
+	items[0].width += limit - first
+
+	best := w.knuthPlassBreaks(items, limit, false)
+	if best == nil {
+		best = w.knuthPlassBreaks(items, limit, true)
+	}
+
+	var lines []string
+	for node := best; node.prev != nil; node = node.prev {
+		var sb strings.Builder
+		for _, item := range items[node.prev.start:node.position] {
+			if item.kind != itemPenalty {
+				sb.WriteString(item.text)
+			}
+		}
+		if node.position < len(items) && items[node.position].kind == itemPenalty {
+			sb.WriteString(items[node.position].text)
+		}
+		lines = append(lines, sb.String())
+	}
+
+	// Reverse lines (we built them backwards)
+	for i, k := 0, len(lines)-1; i < k; i, k = i+1, k-1 {
+		lines[i], lines[k] = lines[k], lines[i]
+	}
+	return lines
+}
+
+// knuthPlassItems converts words into boxes, glue and penalties. Spaces become
+// glue, and other separators stay on the line before a break, like hyphens.
+func (w *wrapping) knuthPlassItems(words []wordWithSep) []kpItem {
+	opts := w.KnuthPlass
+	items := make([]kpItem, 0, len(words)*2)
+
+	// breakAfter adds a penalty to break after the text, flagged if the text
+	// ends with a hyphen.
+	breakAfter := func(text string) {
+		if endsWithHyphen(text) {
+			items = append(items, kpItem{kind: itemPenalty, penalty: opts.HyphenPenalty, flagged: true})
+		} else {
+			items = append(items, kpItem{kind: itemPenalty})
+		}
+	}
+
+	for i, ws := range words {
+		items = append(items, kpItem{kind: itemBox, width: w.stringWidth(ws.word), text: ws.word})
+		if i == len(words)-1 {
+			break
+		}
+		if ws.hyphen {
+			// A discretionary hyphen, which is only written if broken at,
+			// while a soft hyphen is only written if not.
+			hyphen := w.Hyphenation.Hyphen
+			items = append(items, kpItem{kind: itemPenalty, width: w.stringWidth(hyphen), penalty: opts.HyphenPenalty, flagged: true, text: hyphen})
+			if ws.sep != "" {
+				items = append(items, kpItem{kind: itemGlue, text: ws.sep})
+			}
+			continue
+		}
+		if ws.sep == "" {
+			breakAfter(ws.word)
+			continue
+		}
+
+		for sep := ws.sep; sep != ""; {
+			n := w.nextCluster(sep)
+			if sep[:n] == zeroWidthSpace {
+				// An invisible break, which doesn't stretch
+				items = append(items, kpItem{kind: itemGlue, text: sep[:n]})
+				sep = sep[n:]
+				continue
+			}
+			if sep[:n] != " " {
+				items = append(items, kpItem{kind: itemBox, width: w.stringWidth(sep[:n]), text: sep[:n]})
+				breakAfter(sep[:n])
+				sep = sep[n:]
+				continue
+			}
+
+			// A run of spaces is a single glue item
+			for n < len(sep) && sep[n] == ' ' && w.nextCluster(sep[n:]) == 1 {
+				n++
+			}
+			width := float64(w.stringWidth(sep[:n]))
+			items = append(items, kpItem{
+				kind:    itemGlue,
+				width:   int(width),
+				stretch: width * opts.SpaceStretch,
+				shrink:  width * opts.SpaceShrink,
+				text:    sep[:n],
+			})
+			sep = sep[n:]
+		}
+	}
+	return items
+}
+
+// knuthPlassBreaks finds the breaks with the fewest total demerits, returning
+// the node for the end of the paragraph. It returns nil if no breaks keep
+// every line within the tolerance, unless emergency is set, in which case any
+// line is allowed, with extra stretch so that lines without spaces can be
+// compared, and overfull lines are used when nothing else fits.
+func (w *wrapping) knuthPlassBreaks(items []kpItem, limit int, emergency bool) *kpNode {
+	opts := w.KnuthPlass
+
+	// Prefix sums of item widths, stretch and shrink, where penalties don't
+	// count unless they're broken at.
+	widths := make([]int, len(items)+1)
+	stretches := make([]float64, len(items)+1)
+	shrinks := make([]float64, len(items)+1)
+	for i, item := range items {
+		widths[i+1], stretches[i+1], shrinks[i+1] = widths[i], stretches[i], shrinks[i]
+		if item.kind != itemPenalty {
+			widths[i+1] += item.width
+			stretches[i+1] += item.stretch
+			shrinks[i+1] += item.shrink
+		}
+	}
+
+	active := []*kpNode{{fitness: 1}}
+	for b := 0; b <= len(items); b++ {
+		// The end of the paragraph is a forced break
+		final := b == len(items)
+		var penalty float64
+		var flagged bool
+		penaltyWidth := 0
+		switch {
+		case final:
+		case items[b].kind == itemPenalty:
+			penalty, flagged, penaltyWidth = items[b].penalty, items[b].flagged, items[b].width
+		case items[b].kind == itemGlue && b > 0 && items[b-1].kind == itemBox:
+		default:
+			continue
+		}
+
+		// The best way to break here for each fitness class, from lines that
+		// fit and lines that are overfull.
+		var candidates, overfull [4]*kpNode
+		kept := active[:0]
+		for _, a := range active {
+			width := widths[b] - widths[a.start] + penaltyWidth
+			ratio := 0.0
+			switch {
+			case width < limit && final:
+				// The last line is set without stretching
+			case width < limit:
+				stretch := stretches[b] - stretches[a.start]
+				if emergency {
+					// Let every line stretch, even without any spaces, so
+					// that lines can still be told apart by how full they are.
+					stretch += float64(limit)
+				}
+				ratio = math.Inf(1)
+				if stretch > 0 {
+					ratio = float64(limit-width) / stretch
+				}
+			case width > limit:
+				ratio = math.Inf(-1)
+				if shrink := shrinks[b] - shrinks[a.start]; shrink > 0 {
+					ratio = float64(limit-width) / shrink
+				}
+			}
+
+			// A node can't start any more lines once they're too long, or
+			// once a forced break has been passed.
+			if !final && ratio >= -1 {
+				kept = append(kept, a)
+			}
+
+			badness := badness(ratio)
+			if !emergency && (ratio < -1 || badness > opts.Tolerance) {
+				continue
+			}
+
+			demerits := (opts.LinePenalty + badness) * (opts.LinePenalty + badness)
+			if penalty >= 0 {
+				demerits += penalty * penalty
+			} else {
+				demerits -= penalty * penalty
+			}
+			if flagged && a.flagged {
+				demerits += opts.FlaggedDemerits
+			}
+			fitness := fitnessClass(ratio)
+			if fitness-a.fitness > 1 || a.fitness-fitness > 1 {
+				demerits += opts.FitnessDemerits
+			}
+			demerits += a.demerits
+
+			best := &candidates
+			if ratio < -1 {
+				demerits += overfullDemerits * float64(width-limit)
+				best = &overfull
+			}
+			if c := best[fitness]; c == nil || demerits < c.demerits {
+				best[fitness] = &kpNode{fitness: fitness, flagged: flagged, demerits: demerits, prev: a}
+			}
+		}
+		active = kept
+
+		if candidates == [4]*kpNode{} {
+			// Nothing fits, so a line must overflow
+			candidates = overfull
+		}
+		minDemerits := math.Inf(1)
+		for _, c := range candidates {
+			if c != nil && c.demerits < minDemerits {
+				minDemerits = c.demerits
+			}
+		}
+
+		if final {
+			// Choose the best way to end the paragraph
+			for _, c := range candidates {
+				if c != nil && c.demerits == minDemerits {
+					c.position, c.start = b, b
+					return c
+				}
+			}
+			return nil
+		}
+
+		// Only keep breaks whose demerits are close enough to the best that
+		// a later fitness penalty could make them worthwhile.
+		for _, c := range candidates {
+			if c != nil && c.demerits <= minDemerits+opts.FitnessDemerits {
+				c.position, c.start = b, lineStart(items, b)
+				active = append(active, c)
+			}
+		}
+		if len(active) == 0 {
+			return nil
+		}
+	}
+	return nil
+}
+
+// lineStart returns the index of the first item of a line following a break
+// at the item b, skipping any glue and penalties.
+func lineStart(items []kpItem, b int) int {
+	i := b + 1
+	for i < len(items) && items[i].kind != itemBox {
+		i++
+	}
+	return i
+}
+
+// badness returns how badly a line with the given adjustment ratio is spaced,
+// from 0 for perfect spacing up to maxBadness.
+func badness(ratio float64) float64 {
+	if math.IsInf(ratio, 0) {
+		return maxBadness
+	}
+	return math.Min(100*math.Abs(ratio*ratio*ratio), maxBadness)
+}
+
+// fitnessClass classifies the spacing of a line with the given adjustment
+// ratio as tight (0), decent (1), loose (2) or very loose (3).
+func fitnessClass(ratio float64) int {
+	switch {
+	case ratio < -0.5:
+		return 0
+	case ratio <= 0.5:
+		return 1
+	case ratio <= 1:
+		return 2
+	}
+	return 3
+}
+
+// endsWithHyphen reports whether a line broken after s would end with a
+// hyphen or dash.
+func endsWithHyphen(s string) bool {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r == '-' || r == '\u2010'
+}
@@ -0,0 +1,403 @@
+package wrap
+
+import "unicode/utf8"
+
+// Line_Break property values, as defined by UAX #14. The classes resolved
+// away by rule LB1 (AI, CJ, SA, SG and XX) aren't needed.
+const (
+	lbAL = iota
+	lbB2
+	lbBA
+	lbBB
+	lbBK
+	lbCB
+	lbCL
+	lbCM
+	lbCP
+	lbCR
+	lbEB
+	lbEM
+	lbEX
+	lbGL
+	lbH2
+	lbH3
+	lbHL
+	lbHY
+	lbID
+	lbIN
+	lbIS
+	lbJL
+	lbJT
+	lbJV
+	lbLF
+	lbNL
+	lbNS
+	lbNU
+	lbOP
+	lbPO
+	lbPR
+	lbQU
+	lbRI
+	lbSP
+	lbSY
+	lbWJ
+	lbZW
+	lbZWJ
+)
+
+// lineEndChars contains the characters of the classes BK, CR, LF and NL,
+// which force a line break.
+const lineEndChars = "\v\f\r\n\u0085\u2028\u2029"
+
+// Break actions between two characters.
+const (
+	lineBreakProhibited = iota
+	lineBreakAllowed
+	lineBreakMandatory
+)
+
+// lineBreakClass returns the Line_Break property of r.
+func lineBreakClass(r rune) int {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		return lbAL
+	case r >= '0' && r <= '9':
+		return lbNU
+	}
+	return lookupProperty(r, lineBreakClasses, lbAL)
+}
+
+// lineBreaker finds line break opportunities following the Unicode Line
+// Breaking Algorithm described by UAX #14. The zero value is ready to use.
+type lineBreaker struct {
+	started bool
+	// prev is the class of the previous character, after any combining
+	// marks have been attached to it (LB9, LB10).
+	prev int
+	// spaceBase is the class before the spaces when prev is SP (LB8, LB14–17).
+	spaceBase int
+	// zwj is set when the previous character is a zero width joiner (LB8a).
+	zwj bool
+	// hyphenAfterHL is set when prev is HY or BA following HL (LB21a).
+	hyphenAfterHL bool
+	// number tracks the numeric expressions of LB25.
+	number int
+	// regional is the number of consecutive regional indicators (LB30a).
+	regional int
+	// eastAsian is set when the previous character is fullwidth, wide or
+	// halfwidth punctuation (LB30).
+	eastAsian bool
+	// pictographic is set when the previous character is an unassigned
+	// Extended_Pictographic code point (LB30b).
+	pictographic bool
+}
+
+// States of a numeric expression, for LB25.
+const (
+	numberNone   = iota
+	numberDigits // NU (NU | SY | IS)*
+	numberClosed // NU (NU | SY | IS)* (CL | CP)
+)
+
+// next returns the break action before r, and advances past it. rest is the
+// text following r, which is used to look ahead.
+func (lb *lineBreaker) next(r rune, rest string) int {
+	class := lineBreakClass(r)
+	action := lb.action(r, class, rest)
+
+	// LB9: Combining marks take the class of the character they follow, so
+	// the state is unchanged.
+	if (class == lbCM || class == lbZWJ) && lb.started && !isLineEnd(lb.prev) && lb.prev != lbSP && lb.prev != lbZW {
+		lb.zwj = class == lbZWJ
+		return action
+	}
+	lb.zwj = class == lbZWJ
+	if class == lbCM || class == lbZWJ {
+		// LB10: Otherwise treat them as alphabetic.
+		class = lbAL
+	}
+
+	switch {
+	case class == lbNU:
+		lb.number = numberDigits
+	case lb.number == numberDigits && (class == lbSY || class == lbIS):
+	case lb.number == numberDigits && (class == lbCL || class == lbCP):
+		lb.number = numberClosed
+	default:
+		lb.number = numberNone
+	}
+	if class == lbRI {
+		lb.regional++
+	} else {
+		lb.regional = 0
+	}
+	if class == lbSP && lb.prev != lbSP {
+		lb.spaceBase = lb.prev
+	}
+	lb.hyphenAfterHL = (class == lbHY || class == lbBA) && lb.prev == lbHL
+	lb.eastAsian = inRanges(r, eastAsianBrackets)
+	lb.pictographic = class == lbID && inRanges(r, unassignedPictographic)
+	lb.prev = class
+	lb.started = true
+	return action
+}
+
+// action returns the break action before a character of the given class.
+func (lb *lineBreaker) action(r rune, class int, rest string) int {
+	prev := lb.prev
+	// afterSpaces reports whether the text so far ends with c SP*.
+	afterSpaces := func(c int) bool {
+		return prev == c || (prev == lbSP && lb.spaceBase == c)
+	}
+
+	switch {
+	case !lb.started: // LB2
+		return lineBreakProhibited
+	case prev == lbCR && class == lbLF: // LB5
+		return lineBreakProhibited
+	case isLineEnd(prev): // LB4, LB5
+		return lineBreakMandatory
+	case isLineEnd(class): // LB6
+		return lineBreakProhibited
+	case class == lbSP, class == lbZW: // LB7
+		return lineBreakProhibited
+	case afterSpaces(lbZW): // LB8
+		return lineBreakAllowed
+	case lb.zwj: // LB8a
+		return lineBreakProhibited
+	case (class == lbCM || class == lbZWJ) && prev != lbSP: // LB9
+		return lineBreakProhibited
+	}
+
+	if class == lbCM || class == lbZWJ {
+		// LB10
+		class = lbAL
+	}
+
+	switch {
+	case class == lbWJ, prev == lbWJ: // LB11
+		return lineBreakProhibited
+	case prev == lbGL: // LB12
+		return lineBreakProhibited
+	case class == lbGL && prev != lbSP && prev != lbBA && prev != lbHY: // LB12a
+		return lineBreakProhibited
+	case class == lbCL, class == lbCP, class == lbEX, class == lbIS, class == lbSY: // LB13
+		return lineBreakProhibited
+	case afterSpaces(lbOP): // LB14
+		return lineBreakProhibited
+	case class == lbOP && afterSpaces(lbQU): // LB15
+		return lineBreakProhibited
+	case class == lbNS && (afterSpaces(lbCL) || afterSpaces(lbCP)): // LB16
+		return lineBreakProhibited
+	case class == lbB2 && afterSpaces(lbB2): // LB17
+		return lineBreakProhibited
+	case prev == lbSP: // LB18
+		return lineBreakAllowed
+	case class == lbQU, prev == lbQU: // LB19
+		return lineBreakProhibited
+	case class == lbCB, prev == lbCB: // LB20
+		return lineBreakAllowed
+	case class == lbBA, class == lbHY, class == lbNS, prev == lbBB: // LB21
+		return lineBreakProhibited
+	case lb.hyphenAfterHL: // LB21a
+		return lineBreakProhibited
+	case prev == lbSY && class == lbHL: // LB21b
+		return lineBreakProhibited
+	case class == lbIN: // LB22
+		return lineBreakProhibited
+	case isAlphabetic(prev) && class == lbNU, prev == lbNU && isAlphabetic(class): // LB23
+		return lineBreakProhibited
+	case prev == lbPR && (class == lbID || class == lbEB || class == lbEM): // LB23a
+		return lineBreakProhibited
+	case (prev == lbID || prev == lbEB || prev == lbEM) && class == lbPO:
+		return lineBreakProhibited
+	case (prev == lbPR || prev == lbPO) && isAlphabetic(class): // LB24
+		return lineBreakProhibited
+	case isAlphabetic(prev) && (class == lbPR || class == lbPO):
+		return lineBreakProhibited
+	case lb.numeric(class, rest): // LB25
+		return lineBreakProhibited
+	case prev == lbJL && (class == lbJL || class == lbJV || class == lbH2 || class == lbH3): // LB26
+		return lineBreakProhibited
+	case (prev == lbJV || prev == lbH2) && (class == lbJV || class == lbJT):
+		return lineBreakProhibited
+	case (prev == lbJT || prev == lbH3) && class == lbJT:
+		return lineBreakProhibited
+	case isHangul(prev) && class == lbPO, prev == lbPR && isHangul(class): // LB27
+		return lineBreakProhibited
+	case isAlphabetic(prev) && isAlphabetic(class): // LB28
+		return lineBreakProhibited
+	case prev == lbIS && isAlphabetic(class): // LB29
+		return lineBreakProhibited
+	case (isAlphabetic(prev) || prev == lbNU) && class == lbOP && !inRanges(r, eastAsianBrackets): // LB30
+		return lineBreakProhibited
+	case prev == lbCP && !lb.eastAsian && (isAlphabetic(class) || class == lbNU):
+		return lineBreakProhibited
+	case prev == lbRI && class == lbRI && lb.regional%2 == 1: // LB30a
+		return lineBreakProhibited
+	case class == lbEM && (prev == lbEB || lb.pictographic): // LB30b
+		return lineBreakProhibited
+	}
+	return lineBreakAllowed // LB31
+}
+
+// numeric reports whether LB25 prevents a break before a character of the
+// given class, so that numbers such as "$(12.35)" and "-5%" stay together.
+func (lb *lineBreaker) numeric(class int, rest string) bool {
+	prev := lb.prev
+	switch {
+	case (prev == lbPR || prev == lbPO) && class == lbNU:
+		return true
+	case (prev == lbPR || prev == lbPO) && (class == lbOP || class == lbHY):
+		r, _ := utf8.DecodeRuneInString(rest)
+		return rest != "" && lineBreakClass(r) == lbNU
+	case (prev == lbOP || prev == lbHY) && class == lbNU:
+		return true
+	case lb.number == numberDigits:
+		switch class {
+		case lbNU, lbSY, lbIS, lbCL, lbCP, lbPO, lbPR:
+			return true
+		}
+	case lb.number == numberClosed:
+		return class == lbPO || class == lbPR
+	}
+	return false
+}
+
+// cluster advances past the n byte grapheme cluster at the start of s,
+// returning the break action before it.
+func (lb *lineBreaker) cluster(s string, n int) int {
+	action := lineBreakProhibited
+	for i := 0; i < n; {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if a := lb.next(r, s[i+size:]); i == 0 {
+			action = a
+		}
+		i += size
+	}
+	return action
+}
+
+// isLineEnd reports whether the class ends a line (BK, CR, LF and NL).
+func isLineEnd(class int) bool {
+	return class == lbBK || class == lbCR || class == lbLF || class == lbNL
+}
+
+// isAlphabetic reports whether the class is AL or HL.
+func isAlphabetic(class int) bool {
+	return class == lbAL || class == lbHL
+}
+
+// isHangul reports whether the class is one of the Korean syllable blocks.
+func isHangul(class int) bool {
+	return class == lbJL || class == lbJV || class == lbJT || class == lbH2 || class == lbH3
+}
+
+// breakScanner walks the grapheme clusters of a string, finding the line
+// break opportunities between them. Escape sequences are skipped, and
+// opportunities before them are moved to their start, so they begin the
+// following line. Soft hyphens aren't break opportunities here, as words are
+// broken at them by hyphenation, which writes the hyphen, and nor is syntax
+// that would be read differently at the start of the new line.
+type breakScanner struct {
+	w  wrapping
+	s  string
+	lb lineBreaker
+
+	// start and end delimit the current cluster.
+	start, end int
+	// action is the break action at pos, which is the end of the previous
+	// cluster that isn't an escape sequence. That cluster starts at prev.
+	action    int
+	pos, prev int
+}
+
+// scan advances to the next cluster, returning false at the end of the string.
+func (sc *breakScanner) scan() bool {
+	sc.prev, sc.pos = sc.start, sc.end
+	for sc.end < len(sc.s) {
+		n := sc.w.nextCluster(sc.s[sc.end:])
+		sc.start = sc.end
+		sc.end += n
+		if !sc.w.isEscape(sc.s[sc.start:sc.end]) {
+			sc.action = sc.lb.cluster(sc.s[sc.start:], n)
+			if sc.action == lineBreakAllowed && (sc.s[sc.prev:sc.pos] == softHyphen || sc.w.startsSyntax(sc.s[sc.pos:])) {
+				sc.action = lineBreakProhibited
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// unicodeBreak is the equivalent of greedyBreak for UnicodeLineBreaking. The
+// first line ends at the last break opportunity that keeps it within the
+// limit, ignoring trailing spaces, or at a mandatory break.
+func (w *wrapping) unicodeBreak(s string, limit int) (int, int, int, BreakReason) {
+	sc := breakScanner{w: *w, s: s}
+	width := 0
+	fit, over := -1, -1
+	for sc.scan() {
+		switch {
+		case sc.action == lineBreakMandatory:
+			// Drop the character that forced the break
+			return len(trimTrailingSpaces(s[:sc.prev])), sc.pos, sc.end, BreakNewline
+		case sc.action == lineBreakAllowed && over >= 0:
+			// Nothing fit, so take the first opportunity
+			return len(trimTrailingSpaces(s[:sc.pos])), sc.pos, sc.end, BreakOverflow
+		case sc.action == lineBreakAllowed:
+			fit = sc.pos
+		}
+
+		cluster := s[sc.start:sc.end]
+		width += w.advance(cluster, width)
+		if over >= 0 || width <= limit || cluster == " " {
+			continue
+		}
+		over = sc.start
+		if fit >= 0 {
+			return len(trimTrailingSpaces(s[:fit])), fit, sc.end, BreakBreakpoint
+		}
+		if w.CutLongWords {
+			i := w.widthIndex(s, limit)
+			if i == len(s) {
+				return -1, -1, -1, BreakEnd
+			}
+			i = w.cutAwayFromSoftHyphen(s, i)
+			return i, i, sc.end, BreakCut
+		}
+	}
+	return -1, -1, -1, BreakEnd
+}
+
+// mandatoryBreak returns the end of the text before the first mandatory break
+// in s, and the start of the text after it, or -1, -1 if there isn't one.
+func (w *wrapping) mandatoryBreak(s string) (int, int) {
+	sc := breakScanner{w: *w, s: s}
+	for sc.scan() {
+		if sc.action == lineBreakMandatory {
+			return len(trimTrailingSpaces(s[:sc.prev])), sc.pos
+		}
+	}
+	return -1, -1
+}
+
+// splitWordsUnicode is the equivalent of splitWordsWithSep for
+// UnicodeLineBreaking. Each word ends at a break opportunity, and is followed
+// by the spaces before it.
+func (w *wrapping) splitWordsUnicode(s string) []wordWithSep {
+	var words []wordWithSep
+	sc := breakScanner{w: *w, s: s}
+	start := 0
+	for sc.scan() {
+		if sc.action != lineBreakProhibited && sc.pos > start {
+			word := trimTrailingSpaces(s[start:sc.pos])
+			words = append(words, wordWithSep{word: word, sep: s[start+len(word) : sc.pos]})
+			start = sc.pos
+		}
+	}
+	if word := trimTrailingSpaces(s[start:]); word != "" {
+		words = append(words, wordWithSep{word: word, sep: ""})
+	}
+	return words
+}
@@ -0,0 +1,30 @@
+package wrap
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestLineBreaker_Conformance(t *testing.T) {
+	for _, expected := range readBreakTest(t, "LineBreakTest.txt") {
+		input := strings.Join(expected, "")
+
+		var got []string
+		var lb lineBreaker
+		start := 0
+		for i := 0; i < len(input); {
+			r, size := utf8.DecodeRuneInString(input[i:])
+			if lb.next(r, input[i+size:]) != lineBreakProhibited {
+				got = append(got, input[start:i])
+				start = i
+			}
+			i += size
+		}
+		got = append(got, input[start:])
+
+		if strings.Join(got, "÷") != strings.Join(expected, "÷") {
+			t.Errorf("segmenting %+q: got %+q, want %+q", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package wrap
+
+import "strings"
+
+// BreakReason describes why a wrapped line ended.
+type BreakReason int
+
+const (
+	// BreakEnd ends the last line of the input.
+	BreakEnd BreakReason = iota
+
+	// BreakNewline ends a line at a Newline in the input, or at a mandatory
+	// break such as U+2028 LINE SEPARATOR when UnicodeLineBreaking is set.
+	BreakNewline
+
+	// BreakBreakpoint ends a line at one of the Breakpoints, or at a break
+	// opportunity when UnicodeLineBreaking is set.
+	BreakBreakpoint
+
+	// BreakCut ends a line part way through a word wider than the limit, as
+	// CutLongWords is set.
+	BreakCut
+
+	// BreakOverflow ends a line that's wider than the limit, because the word
+	// that overflows it couldn't be broken any earlier.
+	BreakOverflow
+
+	// BreakTruncated ends the last line allowed by MaxLines, when the text
+	// after it was dropped.
+	BreakTruncated
+
+	// BreakHyphen ends a line part way through a word at a hyphenation point,
+	// as Hyphenation is set, and the line's text ends with the hyphen.
+	BreakHyphen
+)
+
+// String returns the name of the reason, such as "newline".
+func (r BreakReason) String() string {
+	switch r {
+	case BreakEnd:
+		return "end"
+	case BreakNewline:
+		return "newline"
+	case BreakBreakpoint:
+		return "breakpoint"
+	case BreakCut:
+		return "cut"
+	case BreakOverflow:
+		return "overflow"
+	case BreakTruncated:
+		return "truncated"
+	case BreakHyphen:
+		return "hyphen"
+	}
+	return "unknown"
+}
+
+// Line describes a single line of wrapped output.
+type Line struct {
+	// Text is the wrapped text of the line, as written between Prefix and
+	// Suffix. It's the input between Start and End, unless the line was
+	// justified or had its tabs expanded, or ends with the hyphen added by
+	// Hyphenation or the Ellipsis added by MaxLines.
+	Text string
+
+	// Start and End are the byte offsets of the line's text in the input.
+	// Breakpoints and spaces dropped at the break aren't included, nor are
+	// TrimInputPrefix, TrimInputSuffix or an indent kept by PreserveIndent.
+	Start, End int
+
+	// Width is the width of Text, as measured by the Wrapper.
+	Width int
+
+	// Break is the reason the line ended.
+	Break BreakReason
+
+	// Prefix is everything written before Text: OutputLinePrefix, then
+	// FirstLinePrefix or ContinuationPrefix along with any indent kept by
+	// PreserveIndent, then any padding added by Alignment and any styles
+	// reapplied by ReapplyStyles.
+	Prefix string
+
+	// Suffix is everything written after Text: the reset closing any styles
+	// reapplied by ReapplyStyles, then any padding added by Alignment, then
+	// OutputLineSuffix.
+	Suffix string
+}
+
+// Lines is shorthand for declaring a new default Wrapper calling its Lines
+// method.
+func Lines(s string, limit int) []Line {
+	return NewWrapper().Lines(s, limit)
+}
+
+// Lines wraps s in the same way as Wrap, but returns a description of each
+// output line rather than the wrapped string. Writing the Prefix, Text and
+// Suffix of every line, separated by Newline, gives the result of Wrap
+// without its trailing newline.
+func (w Wrapper) Lines(s string, limit int) []Line {
+	ww := newWrapping(w)
+	limit = ww.setup(limit)
+	var lines []Line
+	ww.eachLine(s, limit, func(wrapped []Line) {
+		lines = append(lines, wrapped...)
+	})
+	return lines
+}
+
+// eachLine wraps each input line of s in turn, once setup has been called,
+// and passes the wrapped lines to fn, stopping early if MaxLines is reached.
+// The slice passed to fn is reused for the next input line.
+func (w *wrapping) eachLine(s string, limit int, fn func([]Line)) {
+	var lines []Line
+	var st styleState
+	offset, count := 0, 0
+	for {
+		end := len(s)
+		idx := strings.Index(s[offset:], w.Newline)
+		if idx >= 0 {
+			end = offset + idx
+		}
+
+		str, start := s[offset:end], offset
+		if strings.HasPrefix(str, w.TrimInputPrefix) {
+			str, start = str[len(w.TrimInputPrefix):], start+len(w.TrimInputPrefix)
+		}
+		str = strings.TrimSuffix(str, w.TrimInputSuffix)
+		lw := w
+		if w.PreserveIndent {
+			iw, rest := w.withIndent(str)
+			lw = &iw
+			start += len(str) - len(rest)
+			str = rest
+		}
+		lines = lw.lineBuilder(lines[:0], str, start, limit, true)
+		if idx >= 0 {
+			lines[len(lines)-1].Break = BreakNewline
+		}
+		var truncated bool
+		lines, truncated = lw.limitLines(lines, count, limit, true, idx >= 0)
+		count += len(lines)
+		lw.render(lines, limit, true, &st)
+		fn(lines)
+		if idx < 0 || truncated {
+			return
+		}
+		offset = end + len(w.Newline)
+	}
+}
+
+// writeLines writes each line with its prefix and suffix, separated by Newline.
+func (w *wrapping) writeLines(sb *strings.Builder, lines []Line) {
+	for i, line := range lines {
+		if i > 0 {
+			sb.WriteString(w.Newline)
+		}
+		sb.WriteString(line.Prefix)
+		sb.WriteString(line.Text)
+		sb.WriteString(line.Suffix)
+	}
+}
@@ -0,0 +1,622 @@
+package wrap
+
+import "strings"
+
+// WrapMarkdown is shorthand for declaring a new default Wrapper calling its
+// WrapMarkdown method.
+func WrapMarkdown(s string, limit int) string {
+	return NewWrapper().WrapMarkdown(s, limit)
+}
+
+// WrapMarkdown wraps the Markdown document s at the given limit without
+// changing how it renders. Paragraphs, list items and blockquotes are
+// wrapped, with continuation lines indented to stay in their list item and
+// prefixed to stay in their blockquote. Fenced and indented code blocks,
+// tables, headings, thematic breaks, HTML blocks and link reference
+// definitions are left untouched.
+//
+// Lines are never broken inside inline code spans, link destinations or
+// autolinks, nor where the next line would start with syntax that begins a
+// new block, like a list marker or heading.
+//
+// The document structure takes the place of FirstLinePrefix,
+// ContinuationPrefix and PreserveIndent, and lines are always left aligned.
+func (w Wrapper) WrapMarkdown(s string, limit int) string {
+	w.FirstLinePrefix, w.ContinuationPrefix = "", ""
+	w.PreserveIndent = false
+	w.Alignment = AlignLeft
+	ww := newWrapping(w)
+	limit = ww.setup(limit)
+	ww.markdown = true
+
+	lines := strings.Split(s, w.Newline)
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(strings.TrimPrefix(line, w.TrimInputPrefix), w.TrimInputSuffix)
+	}
+
+	var sb strings.Builder
+	for i, line := range ww.markdownBlocks(lines, limit) {
+		if i > 0 {
+			sb.WriteString(w.Newline)
+		}
+		sb.WriteString(w.OutputLinePrefix)
+		sb.WriteString(line)
+		sb.WriteString(w.OutputLineSuffix)
+	}
+	if !w.StripTrailingNewline {
+		sb.WriteString(w.Newline)
+	}
+	return sb.String()
+}
+
+// markdownBlocks returns the lines of a Markdown document, or the contents of
+// a container block, with its paragraphs wrapped at limit.
+func (w *wrapping) markdownBlocks(lines []string, limit int) []string {
+	var out []string
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		cols, _ := mdIndent(line)
+		switch {
+		case isBlankLine(line):
+			out = append(out, "")
+			i++
+
+		case mdFence(line) != "":
+			// Fenced code runs to the closing fence, or the end of the block
+			fence := mdFence(line)
+			j := i + 1
+			for j < len(lines) && !mdFenceEnd(lines[j], fence) {
+				j++
+			}
+			if j < len(lines) {
+				j++
+			}
+			out = append(out, lines[i:j]...)
+			i = j
+
+		case cols >= 4:
+			// Indented code runs until a line that isn't indented enough,
+			// leaving any trailing blank lines outside the block
+			j, end := i+1, i+1
+			for j < len(lines) {
+				if c, _ := mdIndent(lines[j]); c >= 4 && !isBlankLine(lines[j]) {
+					end = j + 1
+				} else if !isBlankLine(lines[j]) {
+					break
+				}
+				j++
+			}
+			out = append(out, lines[i:end]...)
+			i = end
+
+		case mdHeading(line), mdThematicBreak(line), mdLinkReference(line):
+			out = append(out, line)
+			i++
+
+		case mdHTML(line):
+			j := mdHTMLEnd(lines, i)
+			out = append(out, lines[i:j]...)
+			i = j
+
+		case mdTable(lines, i):
+			j := i + 2
+			for j < len(lines) && !isBlankLine(lines[j]) {
+				j++
+			}
+			out = append(out, lines[i:j]...)
+			i = j
+
+		case mdQuote(line):
+			var inner []string
+			j := i
+			for ; j < len(lines); j++ {
+				if mdQuote(lines[j]) {
+					inner = append(inner, mdQuoteContent(lines[j]))
+					continue
+				}
+				// Lazy continuation of a paragraph in the quote
+				if isBlankLine(lines[j]) || isBlankLine(inner[len(inner)-1]) || mdStartsBlock(lines, j) {
+					break
+				}
+				inner = append(inner, lines[j])
+			}
+			for _, l := range w.markdownBlocks(inner, limit-w.stringWidth("> ")) {
+				if l == "" {
+					out = append(out, ">")
+				} else {
+					out = append(out, "> "+l)
+				}
+			}
+			i = j
+
+		case mdListItem(line) > 0:
+			width := mdListItem(line)
+			s := strings.TrimLeft(line, indentSpace)
+			m := listMarkerLength(s)
+			first := strings.Repeat(" ", cols) + s[:m] + strings.Repeat(" ", width-cols-m)
+			inner := []string{mdStripIndent(s[m:], width-cols-m)}
+			j := i + 1
+			for j < len(lines) {
+				if isBlankLine(lines[j]) {
+					// Blank lines belong to the item if more of it follows
+					k := j
+					for k < len(lines) && isBlankLine(lines[k]) {
+						k++
+					}
+					if k == len(lines) {
+						break
+					}
+					if c, _ := mdIndent(lines[k]); c < width {
+						break
+					}
+					for ; j < k; j++ {
+						inner = append(inner, "")
+					}
+					continue
+				}
+				if c, _ := mdIndent(lines[j]); c >= width {
+					inner = append(inner, mdStripIndent(lines[j], width))
+				} else if !isBlankLine(inner[len(inner)-1]) && !mdStartsBlock(lines, j) {
+					// Lazy continuation of a paragraph in the item
+					inner = append(inner, strings.TrimLeft(lines[j], indentSpace))
+				} else {
+					break
+				}
+				j++
+			}
+			rest := strings.Repeat(" ", width)
+			for k, l := range w.markdownBlocks(inner, limit-w.stringWidth(rest)) {
+				switch {
+				case k == 0 && l == "":
+					out = append(out, strings.TrimRight(first, " "))
+				case k == 0:
+					out = append(out, first+l)
+				case l == "":
+					out = append(out, "")
+				default:
+					out = append(out, rest+l)
+				}
+			}
+			i = j
+
+		default:
+			j := i + 1
+			for j < len(lines) && !isBlankLine(lines[j]) && !mdSetextUnderline(lines[j]) && !mdInterrupts(lines, j) {
+				j++
+			}
+			if j < len(lines) && mdSetextUnderline(lines[j]) {
+				// Wrapping a setext heading would change its text
+				out = append(out, lines[i:j+1]...)
+				i = j + 1
+				continue
+			}
+			out = append(out, w.markdownParagraph(lines[i:j], limit)...)
+			i = j
+		}
+	}
+	return out
+}
+
+// markdownParagraph joins and wraps the lines of a paragraph, keeping any
+// hard line breaks.
+func (w *wrapping) markdownParagraph(lines []string, limit int) []string {
+	pw := *w
+	pw.OutputLinePrefix, pw.OutputLineSuffix = "", ""
+	pw.TrimInputPrefix, pw.TrimInputSuffix = "", ""
+	pw.StripTrailingNewline = true
+	wrapText := func(text []string) []string {
+		wrapped, _ := pw.wrap(strings.Join(text, " "), limit)
+		return strings.Split(wrapped, w.Newline)
+	}
+
+	var out, text []string
+	for i, line := range lines {
+		trimmed := strings.Trim(line, indentSpace)
+		text = append(text, trimmed)
+		if i == len(lines)-1 {
+			break
+		}
+		spaces := strings.HasSuffix(line, "  ")
+		if spaces || strings.HasSuffix(trimmed, "\\") {
+			out = append(out, wrapText(text)...)
+			if spaces {
+				out[len(out)-1] += "  "
+			}
+			text = nil
+		}
+	}
+	return append(out, wrapText(text)...)
+}
+
+// isBlankLine reports whether line contains only spaces and tabs.
+func isBlankLine(line string) bool {
+	return strings.TrimLeft(line, indentSpace) == ""
+}
+
+// mdIndent returns the width of the indent at the start of line, with tabs
+// stopping at multiples of four columns, and its length in bytes.
+func mdIndent(line string) (cols, n int) {
+	for ; n < len(line); n++ {
+		switch line[n] {
+		case ' ':
+			cols++
+		case '\t':
+			cols += 4 - cols%4
+		default:
+			return cols, n
+		}
+	}
+	return cols, n
+}
+
+// mdStripIndent removes up to cols columns of indent from the start of line,
+// splitting tabs into spaces where needed.
+func mdStripIndent(line string, cols int) string {
+	c := 0
+	for i := 0; i < len(line) && c < cols; i++ {
+		switch line[i] {
+		case ' ':
+			c++
+		case '\t':
+			c += 4 - c%4
+		default:
+			return line[i:]
+		}
+		if c > cols {
+			return strings.Repeat(" ", c-cols) + line[i+1:]
+		}
+		if c == cols {
+			return line[i+1:]
+		}
+	}
+	return ""
+}
+
+// mdBlock returns line without its indent if it could start a block, as it's
+// indented by at most three columns.
+func mdBlock(line string) (string, bool) {
+	cols, n := mdIndent(line)
+	return line[n:], cols < 4
+}
+
+// mdFence returns the fence that opens a fenced code block on line, or "".
+func mdFence(line string) string {
+	s, ok := mdBlock(line)
+	if !ok || s == "" || (s[0] != '`' && s[0] != '~') {
+		return ""
+	}
+	n := runLength(s, s[0])
+	if n < 3 || (s[0] == '`' && strings.IndexByte(s[n:], '`') >= 0) {
+		return ""
+	}
+	return s[:n]
+}
+
+// mdFenceEnd reports whether line closes the code block opened by fence.
+func mdFenceEnd(line, fence string) bool {
+	s, ok := mdBlock(line)
+	n := runLength(s, fence[0])
+	return ok && n >= len(fence) && isBlankLine(s[n:])
+}
+
+// mdHeading reports whether line is an ATX heading.
+func mdHeading(line string) bool {
+	s, ok := mdBlock(line)
+	n := runLength(s, '#')
+	return ok && n >= 1 && n <= 6 && (n == len(s) || s[n] == ' ' || s[n] == '\t')
+}
+
+// mdThematicBreak reports whether line is a thematic break, made of three or
+// more matching "-", "*" or "_" characters.
+func mdThematicBreak(line string) bool {
+	s, ok := mdBlock(line)
+	if !ok || s == "" || !strings.ContainsRune("-*_", rune(s[0])) {
+		return false
+	}
+	count := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case s[0]:
+			count++
+		case ' ', '\t':
+		default:
+			return false
+		}
+	}
+	return count >= 3
+}
+
+// mdSetextUnderline reports whether line would underline a preceding
+// paragraph as a setext heading.
+func mdSetextUnderline(line string) bool {
+	s, ok := mdBlock(line)
+	s = strings.TrimRight(s, indentSpace)
+	return ok && s != "" && (runLength(s, '=') == len(s) || runLength(s, '-') == len(s))
+}
+
+// mdLinkReference reports whether line starts a link reference definition.
+func mdLinkReference(line string) bool {
+	s, ok := mdBlock(line)
+	if !ok || !strings.HasPrefix(s, "[") {
+		return false
+	}
+	end := strings.Index(s, "]:")
+	return end > 1 && !strings.ContainsAny(s[1:end], "[]")
+}
+
+// mdHTML reports whether line starts an HTML block.
+func mdHTML(line string) bool {
+	s, ok := mdBlock(line)
+	if !ok || len(s) < 2 || s[0] != '<' || autolinkLength(s) > 0 {
+		return false
+	}
+	c := s[1] | 0x20
+	return c >= 'a' && c <= 'z' || s[1] == '/' || s[1] == '!' || s[1] == '?'
+}
+
+// mdHTMLEnd returns the index of the line after the HTML block starting at
+// lines[i]. Comments and raw text elements run to their closing markup, and
+// other blocks to the next blank line.
+func mdHTMLEnd(lines []string, i int) int {
+	s, _ := mdBlock(lines[i])
+	end := ""
+	switch lower := strings.ToLower(s); {
+	case strings.HasPrefix(lower, "<!--"):
+		end = "-->"
+	default:
+		for _, tag := range []string{"pre", "script", "style", "textarea"} {
+			if strings.HasPrefix(lower, "<"+tag) && !isWordByte(lower, len(tag)+1) {
+				end = "</" + tag + ">"
+			}
+		}
+	}
+	if end == "" {
+		for i < len(lines) && !isBlankLine(lines[i]) {
+			i++
+		}
+		return i
+	}
+	for ; i < len(lines); i++ {
+		if strings.Contains(strings.ToLower(lines[i]), end) {
+			return i + 1
+		}
+	}
+	return i
+}
+
+// isWordByte reports whether s has a letter or digit at byte index i.
+func isWordByte(s string, i int) bool {
+	if i >= len(s) {
+		return false
+	}
+	c := s[i] | 0x20
+	return c >= 'a' && c <= 'z' || s[i] >= '0' && s[i] <= '9'
+}
+
+// mdTable reports whether a table starts at lines[i], as it contains a pipe
+// and is followed by a delimiter row.
+func mdTable(lines []string, i int) bool {
+	if i+1 >= len(lines) || !strings.Contains(lines[i], "|") {
+		return false
+	}
+	s, ok := mdBlock(lines[i+1])
+	s = strings.TrimRight(s, indentSpace)
+	return ok && strings.Contains(s, "-") && strings.Trim(s, "|:- \t") == "" &&
+		(strings.Contains(s, "|") || strings.Contains(s, ":"))
+}
+
+// mdQuote reports whether line is part of a blockquote.
+func mdQuote(line string) bool {
+	s, ok := mdBlock(line)
+	return ok && strings.HasPrefix(s, ">")
+}
+
+// mdQuoteContent returns line without its blockquote marker.
+func mdQuoteContent(line string) string {
+	s, _ := mdBlock(line)
+	s = s[1:]
+	if strings.HasPrefix(s, "\t") {
+		// The tab counts as a space, along with any columns after it
+		return mdStripIndent(s, 1)
+	}
+	return strings.TrimPrefix(s, " ")
+}
+
+// mdListItem returns the indent of the content of the list item starting on
+// line, or 0 if line doesn't start one.
+func mdListItem(line string) int {
+	cols, n := mdIndent(line)
+	s := line[n:]
+	if cols >= 4 || mdThematicBreak(line) || strings.HasPrefix(s, "•") || strings.HasPrefix(s, ">") {
+		return 0
+	}
+	m := listMarkerLength(s)
+	if m == 0 || (m < len(s) && s[m] != ' ' && s[m] != '\t') {
+		return 0
+	}
+	spaces, _ := mdIndent(s[m:])
+	if spaces == 0 || spaces > 4 || isBlankLine(s[m:]) {
+		// Content indented further is an indented code block
+		spaces = 1
+	}
+	return cols + m + spaces
+}
+
+// mdInterrupts reports whether lines[i] starts a block that would end a
+// paragraph on the previous line.
+func mdInterrupts(lines []string, i int) bool {
+	line := lines[i]
+	switch {
+	case mdFence(line) != "", mdHeading(line), mdThematicBreak(line), mdQuote(line), mdHTML(line), mdTable(lines, i):
+		return true
+	case mdListItem(line) > 0:
+		// Only non-empty items, with ordered lists starting at one
+		s, _ := mdBlock(line)
+		m := listMarkerLength(s)
+		return !isBlankLine(s[m:]) && (m == 1 || strings.HasPrefix(s, "1") && m == 2)
+	}
+	return false
+}
+
+// mdStartsBlock reports whether lines[i] starts a block after the end of a
+// container, where any list item can start a new list.
+func mdStartsBlock(lines []string, i int) bool {
+	return mdInterrupts(lines, i) || mdListItem(lines[i]) > 0
+}
+
+// runLength returns the number of times c repeats at the start of s.
+func runLength(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+// markdownSpanLength returns the length in bytes of the Markdown code span,
+// autolink or link destination at the start of s, or 0 if s doesn't start
+// with any of these.
+func markdownSpanLength(s string) int {
+	switch s[0] {
+	case '`':
+		return codeSpanLength(s)
+	case '<':
+		return autolinkLength(s)
+	case ']':
+		if n := linkDestinationLength(s[1:]); n > 0 {
+			return n + 1
+		}
+	}
+	return 0
+}
+
+// startsBlock reports whether s, the text after a break, would start a
+// block once the spaces at the start of the new line are trimmed, so that
+// the line can't be broken there.
+func startsBlock(s string) bool {
+	return blockMarkerLength(strings.TrimLeft(s, " ")) > 0
+}
+
+// codeSpanLength returns the length of the code span at the start of s,
+// which ends at the next run of as many backticks as it started with.
+func codeSpanLength(s string) int {
+	n := runLength(s, '`')
+	for i := n; i < len(s); {
+		j := strings.IndexByte(s[i:], '`')
+		if j < 0 {
+			return 0
+		}
+		i += j
+		run := runLength(s[i:], '`')
+		if run == n {
+			return i + n
+		}
+		i += run
+	}
+	return 0
+}
+
+// autolinkLength returns the length of the autolink at the start of s, like
+// <https://example.com> or <user@example.com>.
+func autolinkLength(s string) int {
+	end := strings.IndexByte(s, '>')
+	if end < 0 {
+		return 0
+	}
+	link := s[1:end]
+	if link == "" || strings.ContainsAny(link, " \t<") {
+		return 0
+	}
+	if colon := strings.IndexByte(link, ':'); colon >= 2 && colon <= 32 && isScheme(link[:colon]) {
+		return end + 1
+	}
+	if at := strings.IndexByte(link, '@'); at > 0 && at < len(link)-1 {
+		return end + 1
+	}
+	return 0
+}
+
+// isScheme reports whether s is a valid URI scheme.
+func isScheme(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i] | 0x20
+		switch {
+		case c >= 'a' && c <= 'z':
+		case i > 0 && (s[i] >= '0' && s[i] <= '9' || s[i] == '+' || s[i] == '.' || s[i] == '-'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// linkDestinationLength returns the length of the parenthesised link
+// destination and title at the start of s, or 0 if it isn't closed.
+func linkDestinationLength(s string) int {
+	if s == "" || s[0] != '(' {
+		return 0
+	}
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' && i > 0 && s[i-1] == ' ':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// blockMarkerLength returns the length of the syntax at the start of s that
+// would start a block if it began a line, or 0 if there isn't any.
+func blockMarkerLength(s string) int {
+	if s == "" {
+		return 0
+	}
+	// Markers only count when followed by a space or the end of the text
+	ends := func(n int) int {
+		if n == len(s) || s[n] == ' ' || s[n] == '\t' {
+			return n
+		}
+		return 0
+	}
+	switch c := s[0]; {
+	case c == '>':
+		return 1
+	case c == '#':
+		if n := runLength(s, c); n <= 6 {
+			return ends(n)
+		}
+	case c == '-', c == '+', c == '*', c == '_', c == '=':
+		return ends(runLength(s, c))
+	case c == '`', c == '~':
+		if n := runLength(s, c); n >= 3 {
+			if span := codeSpanLength(s); c == '`' && span > 0 {
+				return span
+			}
+			return n
+		}
+	case c == '<':
+		if mdHTML(s) {
+			return 1
+		}
+	case c >= '0' && c <= '9':
+		if n := listMarkerLength(s); n > 0 {
+			return ends(n)
+		}
+	}
+	return 0
+}
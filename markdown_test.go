@@ -0,0 +1,60 @@
+package wrap
+
+import "testing"
+
+func TestMarkdownSpanLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		length int
+	}{
+		{"code span", "`a b` c", 5},
+		{"double backticks", "``a ` b`` c", 9},
+		{"unclosed code span", "`a b", 0},
+		{"mismatched backticks", "``a` b", 0},
+		{"link destination", "](https://example.com/(x) \"a title\") b", 36},
+		{"unclosed link destination", "](a b", 0},
+		{"autolink", "<https://example.com> b", 21},
+		{"email autolink", "<user@example.com> b", 18},
+		{"not an autolink", "<a b> c", 0},
+		{"space before block syntax", " - b", 0},
+		{"text", "a b", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markdownSpanLength(tt.input); got != tt.length {
+				t.Errorf("markdownSpanLength(%q) = %d, want %d", tt.input, got, tt.length)
+			}
+		})
+	}
+}
+
+func TestStartsBlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected bool
+	}{
+		{"list marker", "- b", true},
+		{"ordered list marker", "  12. b", true},
+		{"heading", "## b", true},
+		{"too many hashes", "####### b", false},
+		{"quote", ">b", true},
+		{"fence", "```go", true},
+		{"html", "<div>", true},
+		{"autolink", "<https://example.com>", false},
+		{"thematic break", " *** b", true},
+		{"hyphenated word", "-b", false},
+		{"number", "12 b", false},
+		{"text", "a b", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := startsBlock(tt.input); got != tt.expected {
+				t.Errorf("startsBlock(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
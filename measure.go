@@ -0,0 +1,152 @@
+package wrap
+
+import "strings"
+
+// Measure returns the number of lines that Wrap would produce for s at the
+// given limit, and the width of the widest of them including its prefixes and
+// suffix, without building the wrapped string.
+func (w Wrapper) Measure(s string, limit int) (lines, width int) {
+	ww := newWrapping(w)
+	limit = ww.setup(limit)
+	return ww.measure(s, limit)
+}
+
+// measure is Measure once setup has been called. Lines wrapped by the greedy
+// algorithm are only measured rather than built and rendered, so measuring
+// doesn't allocate, unless a setting changes the text of a line in a way that
+// has to be rendered to measure. Tabs expanded to spaces only keep their
+// width if a space is one unit wide.
+func (w *wrapping) measure(s string, limit int) (lines, width int) {
+	if w.Algorithm != AlgorithmGreedy || w.Alignment == AlignJustify || w.ReapplyStyles ||
+		w.PreserveIndent || w.MaxLines > 0 || w.Protect.enabled() ||
+		w.Tabs.enabled() && !w.Tabs.Keep && w.Measurer != nil {
+		w.eachLine(s, limit, func(wrapped []Line) {
+			lines += len(wrapped)
+			for _, line := range wrapped {
+				if n := w.startWidth(line.Prefix) + line.Width + w.stringWidth(line.Suffix); n > width {
+					width = n
+				}
+			}
+		})
+		return lines, width
+	}
+
+	for offset := 0; ; {
+		end := len(s)
+		idx := strings.Index(s[offset:], w.Newline)
+		if idx >= 0 {
+			end = offset + idx
+		}
+		str := strings.TrimPrefix(s[offset:end], w.TrimInputPrefix)
+		str = strings.TrimSuffix(str, w.TrimInputSuffix)
+
+		n, widest := w.measureLine(str, limit)
+		lines += n
+		if widest > width {
+			width = widest
+		}
+		if idx < 0 {
+			return lines, width
+		}
+		offset = end + len(w.Newline)
+	}
+}
+
+// measureLine returns the number of lines that lineBuilder wraps the single
+// input line s into with the greedy algorithm, and the width of the widest of
+// them once rendered.
+func (w *wrapping) measureLine(s string, limit int) (lines, width int) {
+	for first := true; ; first = false {
+		s = w.trimBreakpoints(s)
+		w.startLine(first)
+		end, next, _, reason := w.greedyBreak(s, w.lineLimit(limit, first))
+		text := s
+		if next >= 0 {
+			text = trimTrailingSpaces(s[:end])
+		}
+		n := w.stringWidth(text)
+		if reason == BreakHyphen {
+			n += w.stringWidth(w.Hyphenation.Hyphen)
+		}
+		lines++
+		if n = w.lineWidth(n, limit, first); n > width {
+			width = n
+		}
+		if next < 0 {
+			return lines, width
+		}
+		s = s[next:]
+	}
+}
+
+// lineWidth returns the width of a line with text of the given width, once
+// render has added its prefixes, suffix and any padding from Alignment.
+func (w *wrapping) lineWidth(text, limit int, first bool) int {
+	prefix := w.ContinuationPrefix
+	if first {
+		prefix = w.FirstLinePrefix
+	}
+	width := w.stringWidth(w.OutputLinePrefix) + w.stringWidth(prefix)
+	if w.Tabs.enabled() {
+		width = w.lineColumn(first)
+	}
+	width += text + w.stringWidth(w.OutputLineSuffix)
+
+	if extra := w.lineLimit(limit, first) - text; extra > 0 && w.Alignment != AlignLeft {
+		left := extra
+		if w.Alignment == AlignCenter {
+			left = extra / 2
+		}
+		width += w.fillWidth(left)
+		if w.OutputLineSuffix != "" {
+			width += w.fillWidth(extra - left)
+		}
+	}
+	return width
+}
+
+// LineCount returns the number of lines that Wrap would produce for s at the
+// given limit, which is the height of the wrapped text.
+func (w Wrapper) LineCount(s string, limit int) int {
+	lines, _ := w.Measure(s, limit)
+	return lines
+}
+
+// MaxWidth returns the width of the widest line that Wrap would produce for s
+// at the given limit, including its prefixes and suffix.
+func (w Wrapper) MaxWidth(s string, limit int) int {
+	_, width := w.Measure(s, limit)
+	return width
+}
+
+// WidthForLines returns the smallest limit at which s wraps into no more than
+// the given number of lines, found by a binary search over the limits that
+// change how s is wrapped. MaxLines is ignored. It returns -1 if s can't be
+// wrapped into that few lines at any limit, such as when it contains more
+// newlines.
+//
+// The number of lines is assumed to shrink as the limit grows, which always
+// holds for AlgorithmGreedy. The optimal algorithms may occasionally use one
+// more line at a wider limit, in which case the limit returned is still one
+// that fits, but there may be a narrower one.
+func (w Wrapper) WidthForLines(s string, lines int) int {
+	w.MaxLines = 0
+
+	// Nothing is wrapped at the width of the widest unwrapped line
+	lo, hi := 1, w.MaxWidth(s, 0)
+	if hi < 1 {
+		hi = 1
+	}
+	if w.LineCount(s, hi) > lines {
+		return -1
+	}
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if w.LineCount(s, mid) <= lines {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return hi
+}
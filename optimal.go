@@ -1,60 +1,281 @@
 package wrap
 
 import (
+	"math"
 	"strings"
-	"unicode/utf8"
 )
 
+// infinity is the cost of each column a line overflows the limit by with
+// AlgorithmMinimumRaggedness, unless widows are penalised or words are
+// hyphenated, as it's always been.
 const infinity = 1e20
 
-// wordWithSep stores a word along with the separator that followed it.
+// WidowOptions penalises a short last line of a paragraph, known as a widow,
+// when breaking lines with AlgorithmMinimumRaggedness or AlgorithmBalanced.
+// Penalties are added to the cost of the paragraph, which is the sum of the
+// squares of the space left at the end of each line, so a penalty of 100 is
+// worth as much as 10 columns left over on a line that would otherwise be
+// full. A paragraph that fits on a single line is never penalised.
+//
+// Setting any of the options also makes AlgorithmMinimumRaggedness weigh
+// lines that overflow the limit as it does for hyphenated text, and as
+// AlgorithmBalanced always does, adding the cost of the lines before them so
+// that the rest of the paragraph is kept within the limit where it can be.
+// Otherwise, its output is unchanged from earlier releases.
+type WidowOptions struct {
+	// MinWidth is the narrowest the last line can be without adding Penalty.
+	// Default: 0
+	MinWidth int
+
+	// Penalty is added if the last line is narrower than MinWidth.
+	// Default: 0
+	Penalty float64
+
+	// OrphanPenalty is added if the last line holds a single word.
+	// Default: 0
+	OrphanPenalty float64
+}
+
+// wordWithSep stores a word along with the separator that followed it. The
+// word is empty between breakpoints that a line can be broken between.
 type wordWithSep struct {
 	word string
 	sep  string // separator after this word (empty for last word)
+	// hyphen is set if the word is part of a longer one, broken at a
+	// hyphenation point, so a line broken after it ends with a hyphen.
+	hyphen bool
+}
+
+// tail returns the text written at the end of a line broken after ws, which
+// is the hyphen if it was hyphenated, or any separator other than spaces.
+func (w *wrapping) tail(ws wordWithSep) string {
+	if ws.hyphen {
+		return w.Hyphenation.Hyphen
+	}
+	return trimTrailingSpaces(ws.sep)
+}
+
+// hyphenateWords splits each word at its hyphenation points, if it has any.
+// A soft hyphen a word is split at becomes the separator after the first part.
+func (w *wrapping) hyphenateWords(words []wordWithSep) []wordWithSep {
+	var result []wordWithSep
+	for k, ws := range words {
+		points := w.hyphenPoints(ws.word)
+		if points == nil && result == nil {
+			continue
+		}
+		if result == nil {
+			result = append(make([]wordWithSep, 0, len(words)+len(points)), words[:k]...)
+		}
+		prev := 0
+		for _, p := range points {
+			part := wordWithSep{word: ws.word[prev:p], hyphen: true}
+			if strings.HasPrefix(ws.word[p:], softHyphen) {
+				part.sep = softHyphen
+			}
+			result = append(result, part)
+			prev = p + len(part.sep)
+		}
+		result = append(result, wordWithSep{word: ws.word[prev:], sep: ws.sep})
+	}
+	if result == nil {
+		return words
+	}
+	return result
 }
 
-// lineBuilderOptimal writes wrapped lines using minimum raggedness algorithm.
-func (w Wrapper) lineBuilderOptimal(sb *strings.Builder, s string, limit int) {
+// lineBuilderOptimal appends lines wrapped using the minimum raggedness or
+// Knuth–Plass algorithm. start is the offset of s in the input, and first is
+// set if s starts a paragraph.
+func (w *wrapping) lineBuilderOptimal(lines []Line, s string, start, limit int, first bool) []Line {
 	if s == "" {
-		sb.WriteString(w.OutputLinePrefix)
-		sb.WriteString(w.OutputLineSuffix)
-		return
+		return append(lines, newLine("", start, BreakEnd))
+	}
+
+	// The optimal algorithm has no notion of a forced break, so wrap the text
+	// on either side of one separately.
+	if w.UnicodeLineBreaking {
+		if end, next := w.mandatoryBreak(s); next >= 0 {
+			lines = w.lineBuilderOptimal(lines, s[:end], start, limit, first)
+			lines[len(lines)-1].Break = BreakNewline
+			return w.lineBuilder(lines, s[next:], start+next, limit, false)
+		}
+	}
+
+	// Where a tab ends up on its line isn't known until the lines are chosen
+	if w.Tabs.enabled() {
+		w.column = unknownColumn
+	}
+	firstLimit, restLimit := w.lineLimit(limit, first), w.lineLimit(limit, false)
+	var wrapped []string
+	switch w.Algorithm {
+	case AlgorithmKnuthPlass:
+		wrapped = w.wrapKnuthPlassLines(s, firstLimit, restLimit)
+	case AlgorithmBalanced:
+		wrapped = w.wrapBalancedLines(s, firstLimit, restLimit)
+	default:
+		wrapped = w.wrapOptimalLines(s, firstLimit, restLimit)
 	}
 
-	lines := w.wrapOptimalLines(s, limit)
-	for i, line := range lines {
-		sb.WriteString(w.OutputLinePrefix)
-		sb.WriteString(line)
-		sb.WriteString(w.OutputLineSuffix)
-		if i < len(lines)-1 {
-			sb.WriteString(w.Newline)
+	// Lines are separated by any spaces dropped at each break. A line broken
+	// part way through a word of s was cut.
+	var words [][2]int
+	if w.CutLongWords {
+		offset := 0
+		for _, ws := range w.splitWordsWithSep(s) {
+			words = append(words, [2]int{offset, offset + len(ws.word)})
+			offset += len(ws.word) + len(ws.sep)
 		}
 	}
+	// A line ending with a hyphen that isn't in s was hyphenated.
+	offset, k := 0, 0
+	for i, text := range wrapped {
+		body := text
+		hyphenated := false
+		for offset < len(s) && !strings.HasPrefix(s[offset:], text) {
+			if w.hyphenating(s) && i < len(wrapped)-1 && len(text) > len(w.Hyphenation.Hyphen) && strings.HasSuffix(text, w.Hyphenation.Hyphen) {
+				body = strings.TrimSuffix(text, w.Hyphenation.Hyphen)
+				if hyphenated = strings.HasPrefix(s[offset:], body); hyphenated {
+					break
+				}
+				body = text
+			}
+			offset += w.nextCluster(s[offset:])
+		}
+		end := offset + len(body)
+
+		reason := BreakEnd
+		if i < len(wrapped)-1 {
+			lineLimit := restLimit
+			if i == 0 {
+				lineLimit = firstLimit
+			}
+			for k < len(words) && words[k][1] <= end {
+				k++
+			}
+			switch {
+			case hyphenated:
+				reason = BreakHyphen
+			case k < len(words) && words[k][0] < end:
+				reason = BreakCut
+			case w.stringWidth(text) > lineLimit:
+				reason = BreakOverflow
+			default:
+				reason = BreakBreakpoint
+			}
+		}
+		line := newLine(body, start+offset, reason)
+		line.Text = text
+		lines = append(lines, line)
+		offset = end
+	}
+	return lines
 }
 
-// wrapOptimalLines wraps text using minimum raggedness algorithm.
-// Returns a slice of lines. Uses SMAWK-based approach for O(n) time complexity.
-func (w Wrapper) wrapOptimalLines(s string, limit int) []string {
+// wrapOptimalLines wraps text using minimum raggedness algorithm, where the
+// first line may have a different limit to the rest.
+// Returns a slice of lines.
+func (w *wrapping) wrapOptimalLines(s string, first, limit int) []string {
 	// Split into words, preserving separators
-	words := w.splitWordsWithSep(s)
+	words := w.hyphenateWords(w.splitWordsWithSep(s))
 	if len(words) == 0 {
 		return []string{""}
 	}
 
 	// Handle CutLongWords: split any words longer than limit
 	if w.CutLongWords {
-		words = w.cutLongWordsInListWithSep(words, limit)
+		words = w.cutLongWordsInListWithSep(words, first, limit)
 	}
+	return w.optimalLines(words, first, limit)
+}
 
+// wrapBalancedLines wraps text into the number of lines the greedy algorithm
+// would use, with lines of about the same width, where the first line may
+// have a different limit to the rest. This is done by finding the narrowest
+// limit that needs no more lines, and breaking lines with the minimum
+// raggedness algorithm at that limit, which counts the space left on the last
+// line as much as any other.
+func (w *wrapping) wrapBalancedLines(s string, first, limit int) []string {
+	words := w.hyphenateWords(w.splitWordsWithSep(s))
+	if len(words) == 0 {
+		return []string{""}
+	}
+	if w.CutLongWords {
+		words = w.cutLongWordsInListWithSep(words, first, limit)
+	}
+
+	lines := w.greedyLineCount(words, first, limit)
+	if lines == 1 {
+		return w.optimalLines(words, first, limit)
+	}
+
+	// Narrowing the limit past the widest word only makes it overflow
+	lo, hi := 1, limit
+	for _, ws := range words {
+		if n := w.stringWidth(ws.word + w.tail(ws)); n > lo {
+			lo = n
+		}
+	}
+	if lo > hi {
+		lo = hi
+	}
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if w.greedyLineCount(words, first-(limit-mid), mid) <= lines {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return w.optimalLines(words, first-(limit-hi), hi)
+}
+
+// greedyLineCount returns the number of lines the greedy algorithm breaks
+// words into, where the first line may have a different limit to the rest.
+func (w *wrapping) greedyLineCount(words []wordWithSep, first, limit int) int {
+	count, width, lineLimit := 1, 0, first
+	for i, ws := range words {
+		wordLen := w.stringWidth(ws.word)
+		if i == 0 {
+			width = wordLen
+			continue
+		}
+		// A separator like a hyphen stays at the end of a line broken after it
+		sep := words[i-1].sep
+		tail := w.stringWidth(w.tail(ws))
+		next := width + w.stringWidth(sep) + wordLen + tail
+		if next <= lineLimit {
+			width = next - tail
+			continue
+		}
+		count++
+		width, lineLimit = wordLen, limit
+	}
+	return count
+}
+
+// optimalLines breaks words into lines using the minimum raggedness
+// algorithm, where the first line may have a different limit to the rest.
+// Uses SMAWK-based approach for O(n) time complexity.
+func (w *wrapping) optimalLines(words []wordWithSep, first, limit int) []string {
 	count := len(words)
 
 	// Precompute word and separator lengths for O(1) line width calculation
+	// Separators other than spaces, like hyphens, stay at the end of a line
+	// broken after them, so tailLens holds their widths.
 	wordLens := make([]int, count)
 	sepLens := make([]int, count)
+	tailLens := make([]int, count)
+	hyphenated := false
 	for i, ws := range words {
-		wordLens[i] = utf8.RuneCountInString(ws.word)
-		sepLens[i] = utf8.RuneCountInString(ws.sep)
+		wordLens[i] = w.stringWidth(ws.word)
+		sepLens[i] = w.stringWidth(ws.sep)
+		tailLens[i] = w.stringWidth(w.tail(ws))
+		hyphenated = hyphenated || ws.hyphen
 	}
+	// Every line starting with the first word is the first line, so widen
+	// or narrow that word to make up the difference in limits.
+	wordLens[0] += limit - first
 
 	// Prefix sums for O(1) range queries
 	// wordOffsets[j] = sum of word lengths for words[0:j]
@@ -66,31 +287,57 @@ func (w Wrapper) wrapOptimalLines(s string, limit int) []string {
 		sepOffsets[i+1] = sepOffsets[i] + sepLens[i]
 	}
 
+	// Overflowing lines cost a fixed amount per column, as they always have,
+	// unless the lines are balanced, widows are penalised or words are
+	// hyphenated, none of which earlier releases did.
+	fixedOverflow := w.Algorithm == AlgorithmMinimumRaggedness && w.Widows == (WidowOptions{}) && !hyphenated
+
 	// minima[j] = minimum cost to break words[0:j]
+	// Costs start out above that of any overflowing line, so that columns
+	// where every line overflows are still filled in.
+	unset := math.Inf(1)
+	if fixedOverflow {
+		unset = infinity
+	}
 	minima := make([]float64, count+1)
 	for i := 1; i <= count; i++ {
-		minima[i] = infinity
+		minima[i] = unset
 	}
 
 	// breaks[j] = optimal break point for line ending at word j
 	breaks := make([]int, count+1)
 
+	// Each column a line overflows by costs more than every line that fits
+	// put together, so that as little as possible overflows, but not so much
+	// that the cost of the lines that fit is lost to rounding.
+	widest := math.Max(float64(first), float64(limit))
+	overflow := float64(count)*(widest*widest+math.Abs(w.Hyphenation.Penalty)) +
+		math.Abs(w.Widows.Penalty) + math.Abs(w.Widows.OrphanPenalty) + 1
+
 	// cost calculates the cost of a line from word i to word j-1
-	// Line width = sum of word lengths + separators between words (not after last word)
+	// Line width = sum of word lengths + separators between words (not after last word),
+	// plus any hyphen kept at the end of the line
 	cost := func(i, j int) float64 {
 		if i >= j {
-			return infinity
+			return unset
 		}
 		// Words from i to j-1: wordOffsets[j] - wordOffsets[i]
 		// Separators from i to j-2: sepOffsets[j-1] - sepOffsets[i]
-		lineWidth := wordOffsets[j] - wordOffsets[i]
+		lineWidth := wordOffsets[j] - wordOffsets[i] + tailLens[j-1]
 		if j > i+1 {
 			lineWidth += sepOffsets[j-1] - sepOffsets[i]
 		}
-		if lineWidth > limit {
+		if lineWidth > limit && fixedOverflow {
 			return infinity * float64(lineWidth-limit)
 		}
-		return minima[i] + float64((limit-lineWidth)*(limit-lineWidth))
+		if lineWidth > limit {
+			return minima[i] + overflow*float64(lineWidth-limit)
+		}
+		c := minima[i] + float64((limit-lineWidth)*(limit-lineWidth))
+		if words[j-1].hyphen {
+			c += w.Hyphenation.Penalty
+		}
+		return c
 	}
 
 	// SMAWK-based algorithm using divide and conquer with online matrix
@@ -193,10 +440,36 @@ func (w Wrapper) wrapOptimalLines(s string, limit int) []string {
 		}
 	}
 
-	// If SMAWK didn't find a valid solution (minima still at infinity),
-	// fall back to simple greedy line breaking
-	if minima[count] >= infinity {
-		return w.greedyWrapWithSep(words, limit)
+	// Penalties for the last line only change the last column of costs, so
+	// rather than upset SMAWK, choose the start of the last line again,
+	// keeping SMAWK's choice unless a penalty makes another one cheaper.
+	if w.Widows != (WidowOptions{}) && count > 1 {
+		penalty := func(i int) float64 {
+			// A single line is never a widow
+			if i == 0 {
+				return 0
+			}
+			var p float64
+			if wordOffsets[count]-wordOffsets[i]+sepOffsets[count-1]-sepOffsets[i]+tailLens[count-1] < w.Widows.MinWidth {
+				p += w.Widows.Penalty
+			}
+			if i == count-1 {
+				p += w.Widows.OrphanPenalty
+			}
+			return p
+		}
+		minima[count] += penalty(breaks[count])
+		for i := 0; i < count; i++ {
+			if c := cost(i, count) + penalty(i); c < minima[count] {
+				minima[count], breaks[count] = c, i
+			}
+		}
+	}
+
+	// If SMAWK didn't find a valid solution (minima still unset), fall back
+	// to simple greedy line breaking
+	if minima[count] >= unset {
+		return w.greedyWrapWithSep(words, first, limit)
 	}
 
 	// Reconstruct lines from break points, preserving original separators
@@ -206,7 +479,7 @@ func (w Wrapper) wrapOptimalLines(s string, limit int) []string {
 		i := breaks[j]
 		// Safety check: if breaks[j] == j, we'd loop forever
 		if i >= j {
-			return w.greedyWrapWithSep(words, limit)
+			return w.greedyWrapWithSep(words, first, limit)
 		}
 		// Build line with original separators
 		var sb strings.Builder
@@ -216,6 +489,7 @@ func (w Wrapper) wrapOptimalLines(s string, limit int) []string {
 				sb.WriteString(words[k].sep)
 			}
 		}
+		sb.WriteString(w.tail(words[j-1]))
 		lines = append(lines, sb.String())
 		j = i
 	}
@@ -230,7 +504,7 @@ func (w Wrapper) wrapOptimalLines(s string, limit int) []string {
 
 // greedyWrapWithSep provides a fallback greedy algorithm for cases where
 // the SMAWK algorithm doesn't find a valid solution.
-func (w Wrapper) greedyWrapWithSep(words []wordWithSep, limit int) []string {
+func (w *wrapping) greedyWrapWithSep(words []wordWithSep, first, limit int) []string {
 	if len(words) == 0 {
 		return []string{""}
 	}
@@ -238,70 +512,76 @@ func (w Wrapper) greedyWrapWithSep(words []wordWithSep, limit int) []string {
 	var lines []string
 	var sb strings.Builder
 	lineLen := 0
+	lineLimit := first
 
 	for i, ws := range words {
-		wordLen := utf8.RuneCountInString(ws.word)
+		wordLen := w.stringWidth(ws.word)
 		sepLen := 0
 		if i < len(words)-1 {
-			sepLen = utf8.RuneCountInString(ws.sep)
+			sepLen = w.stringWidth(ws.sep)
 		}
 
-		if sb.Len() == 0 {
+		if i == 0 {
 			// First word on line
 			sb.WriteString(ws.word)
 			lineLen = wordLen
-		} else if lineLen+sepLen+wordLen <= limit {
+		} else if lineLen+sepLen+wordLen <= lineLimit {
 			// Word fits on current line
 			sb.WriteString(words[i-1].sep)
 			sb.WriteString(ws.word)
 			lineLen += sepLen + wordLen
 		} else {
 			// Word doesn't fit, start new line
+			sb.WriteString(w.tail(words[i-1]))
 			lines = append(lines, sb.String())
 			sb.Reset()
 			sb.WriteString(ws.word)
 			lineLen = wordLen
+			lineLimit = limit
 		}
 	}
 
-	if sb.Len() > 0 {
-		lines = append(lines, sb.String())
-	}
-
-	return lines
+	return append(lines, sb.String())
 }
 
 // splitWordsWithSep splits a string into words, preserving the separators between them.
-func (w Wrapper) splitWordsWithSep(s string) []wordWithSep {
-	s = strings.TrimLeft(s, w.Breakpoints)
+// Words and separators are made of whole grapheme clusters.
+func (w *wrapping) splitWordsWithSep(s string) []wordWithSep {
+	s = w.trimBreakpoints(s)
 	if s == "" {
 		return nil
 	}
+	if w.UnicodeLineBreaking {
+		return w.splitWordsUnicode(s)
+	}
 
 	var words []wordWithSep
 	var current strings.Builder
 	var sep strings.Builder
 
 	inWord := true
-	for _, r := range s {
-		if strings.ContainsRune(w.Breakpoints, r) {
+	for s != "" {
+		n := w.nextCluster(s)
+		cluster := s[:n]
+		s = s[n:]
+		if w.canBreakAt(cluster, s) {
 			if inWord && current.Len() > 0 {
 				inWord = false
 			}
 			if !inWord {
-				sep.WriteRune(r)
+				sep.WriteString(cluster)
 			}
 		} else {
 			if !inWord {
 				// End of separator, save word with its separator
 				if current.Len() > 0 {
-					words = append(words, wordWithSep{word: current.String(), sep: sep.String()})
+					words = w.appendWordWithSep(words, current.String(), sep.String())
 					current.Reset()
 					sep.Reset()
 				}
 				inWord = true
 			}
-			current.WriteRune(r)
+			current.WriteString(cluster)
 		}
 	}
 
@@ -312,36 +592,62 @@ func (w Wrapper) splitWordsWithSep(s string) []wordWithSep {
 	return words
 }
 
-// cutLongWordsInListWithSep splits any words longer than limit into chunks.
-func (w Wrapper) cutLongWordsInListWithSep(words []wordWithSep, limit int) []wordWithSep {
-	if limit < 1 {
+// appendWordWithSep appends word to words, followed by sep, a run of
+// breakpoints. A line can be broken at any of them, as by the greedy
+// algorithm, so the run is split before each one after the first that stays
+// on the line, like the hyphen in " -", with empty words between the pieces.
+func (w *wrapping) appendWordWithSep(words []wordWithSep, word, sep string) []wordWithSep {
+	start := 0
+	for i := 0; i < len(sep); {
+		n := w.nextCluster(sep[i:])
+		if i > start && sep[i:i+n] != " " && sep[i:i+n] != zeroWidthSpace {
+			words = append(words, wordWithSep{word: word, sep: sep[start:i]})
+			word, start = "", i
+		}
+		i += n
+	}
+	return append(words, wordWithSep{word: word, sep: sep[start:]})
+}
+
+// cutLongWordsInListWithSep splits any words wider than limit into chunks,
+// where the first chunk of the first word is cut at the first line's limit.
+// A word's tail, such as the hyphen of a hyphenated part, is counted with its
+// last chunk, which is written before it at the end of a line.
+func (w *wrapping) cutLongWordsInListWithSep(words []wordWithSep, first, limit int) []wordWithSep {
+	if first < 1 || limit < 1 {
 		return words
 	}
 
 	result := make([]wordWithSep, 0, len(words))
-	for _, ws := range words {
-		wordLen := utf8.RuneCountInString(ws.word)
-		if wordLen <= limit {
-			result = append(result, ws)
-			continue
+	for i, ws := range words {
+		chunkLimit := limit
+		if i == 0 {
+			chunkLimit = first
 		}
-
-		// Split word into chunks of limit runes
-		runes := []rune(ws.word)
-		for i := 0; i < len(runes); i += limit {
-			end := i + limit
-			if end > len(runes) {
-				end = len(runes)
+		tail := w.stringWidth(w.tail(ws))
+
+		// Split word into chunks no wider than limit. Only the last chunk
+		// keeps the original separator.
+		word := ws.word
+		for {
+			if w.stringWidth(word)+tail <= chunkLimit {
+				result = append(result, wordWithSep{word: word, sep: ws.sep, hyphen: ws.hyphen})
+				break
 			}
-			chunk := wordWithSep{word: string(runes[i:end]), sep: ""}
-			// Only the last chunk keeps the original separator
-			if end == len(runes) {
-				chunk.sep = ws.sep
+			end := w.widthIndex(word, chunkLimit)
+			if end == len(word) {
+				// The rest fits, but not with its tail, so leave room for it
+				// after a last chunk, unless the tail can't fit at all
+				if chunkLimit <= tail {
+					result = append(result, wordWithSep{word: word, sep: ws.sep, hyphen: ws.hyphen})
+					break
+				}
+				end = w.widthIndex(word, chunkLimit-tail)
 			}
-			result = append(result, chunk)
+			result = append(result, wordWithSep{word: word[:end]})
+			word = word[end:]
+			chunkLimit = limit
 		}
 	}
 	return result
 }
-
-
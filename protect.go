@@ -0,0 +1,310 @@
+package wrap
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ProtectOptions selects text that lines are never broken within, such as
+// URLs containing hyphens. A protected span that doesn't fit on the rest of a
+// line is moved to the next line as a whole, and one that's wider than the
+// limit overflows it, unless CutLongWords is set, in which case it's cut like
+// any other long word. Trailing punctuation, such as a full stop after a URL,
+// isn't protected.
+type ProtectOptions struct {
+	// URLs protects URLs with a scheme, such as https://example.com/a-b, and
+	// those starting with www.
+	// Default: false
+	URLs bool
+
+	// Emails protects email addresses, such as first-last@example.com.
+	// Default: false
+	Emails bool
+
+	// Paths protects file paths, which start with /, ~/, ./, ../ or a drive
+	// letter such as C:\, or contain a slash between names, like
+	// cmd/wrap/main.go.
+	// Default: false
+	Paths bool
+
+	// Code protects code spans between backticks, such as `go test -run`.
+	// Default: false
+	Code bool
+
+	// Pattern protects the text matched by a regular expression, such as
+	// `JIRA-\d+|v\d+\.\d+\.\d+`. It's matched at the start of every grapheme
+	// cluster in turn, as if it began with \A, and the longest match is used,
+	// as if Longest had been called, as is the longest span found by any of
+	// the options.
+	// Default: nil
+	Pattern *regexp.Regexp
+}
+
+// enabled reports whether any spans are protected.
+func (o ProtectOptions) enabled() bool {
+	return o.URLs || o.Emails || o.Paths || o.Code || o.Pattern != nil
+}
+
+// length returns the length in bytes of the longest protected span at the
+// start of s, or 0 if there isn't one. pattern is Pattern as anchored by
+// anchored.
+func (o ProtectOptions) length(s string, pattern *regexp.Regexp) int {
+	n := 0
+	longest := func(m int) {
+		if m > n {
+			n = m
+		}
+	}
+	if o.URLs {
+		longest(urlLength(s))
+	}
+	if o.Emails {
+		longest(emailLength(s))
+	}
+	if o.Paths {
+		longest(pathLength(s))
+	}
+	if o.Code && s[0] == '`' {
+		// A run of backticks that doesn't start a code span is literal text,
+		// so a span can't start part way through it either.
+		if m := codeSpanLength(s); m > 0 {
+			longest(m)
+		} else {
+			longest(runLength(s, '`'))
+		}
+	}
+	if pattern != nil {
+		if loc := pattern.FindStringIndex(s); loc != nil {
+			longest(loc[1])
+		}
+	}
+	return n
+}
+
+// protecting reports whether w keeps any spans in single unbreakable
+// clusters.
+func (w *wrapping) protecting() bool {
+	return w.markdown || w.Protect.enabled()
+}
+
+// protectedLength returns the length in bytes of the span at the start of s
+// that mustn't be broken, or 0 if there isn't one.
+func (w *wrapping) protectedLength(s string) int {
+	if !w.protecting() || s == "" {
+		return 0
+	}
+	if w.markdown {
+		if n := markdownSpanLength(s); n > 0 {
+			return n
+		}
+	}
+	return w.Protect.length(s, w.pattern)
+}
+
+// cutIndex returns the byte index at which the cluster at the start of s,
+// n bytes long and wider than limit on its own, can be cut so that the text
+// before it fits within limit, or 0 if it can't be cut. Only spans protected
+// by ProtectOptions can be, as cutting Markdown syntax would change how it
+// renders.
+func (w *wrapping) cutIndex(s string, n, limit int) int {
+	if n == nextGrapheme(s) || w.isEscape(s[:n]) || w.markdown && markdownSpanLength(s) > 0 {
+		return 0
+	}
+	return w.graphemeIndex(s[:n], limit)
+}
+
+// graphemeIndex returns the byte index of the end of the longest run of
+// grapheme clusters at the start of s whose width does not exceed limit,
+// ignoring any protected spans, or len(s) if the whole of s fits. At least
+// one grapheme cluster is always kept.
+func (w *wrapping) graphemeIndex(s string, limit int) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if n := escapeLength(s[i:]); w.EscapeSequences && n > 0 {
+			i += n
+			continue
+		}
+		n := nextGrapheme(s[i:])
+		width += w.advance(s[i:i+n], width)
+		if width > limit && i > 0 {
+			return i
+		}
+		i += n
+	}
+	return len(s)
+}
+
+// cutSpan passes a line to emit for each piece of the rest of a protected
+// span, from next up to n in s, that has to be cut to fit within limit, so
+// that it isn't broken at any breakpoints inside it. It returns the start of
+// the text after them.
+func (w *wrapping) cutSpan(s string, start, next, n, limit int, emit func(Line)) int {
+	for {
+		w.startLine(false)
+		limit := w.lineLimit(limit, false)
+		if limit < 1 {
+			return next
+		}
+		i := next + w.graphemeIndex(s[next:n], limit)
+		if i == n {
+			return next
+		}
+		emit(newLine(s[next:i], start+next, BreakCut))
+		next = i
+	}
+}
+
+// anchored returns a copy of re that only matches at the start of the text,
+// preferring the longest match, or nil if re is nil.
+func anchored(re *regexp.Regexp) *regexp.Regexp {
+	if re == nil {
+		return nil
+	}
+	a := regexp.MustCompile(`\A(?:` + re.String() + `)`)
+	a.Longest()
+	return a
+}
+
+// Limits on how far ahead the start of a URL, email address or relative path
+// is searched for, so that the search takes constant time at each cluster.
+const (
+	maxSchemeLength    = 32
+	maxLocalPartLength = 64
+	maxNameLength      = 255
+)
+
+// urlLength returns the length of the URL at the start of s, which starts
+// with a scheme followed by :// or with www., or 0 if there isn't one.
+func urlLength(s string) int {
+	start := 0
+	if len(s) >= 4 && strings.EqualFold(s[:4], "www.") {
+		start = 4
+	} else {
+		i := strings.Index(s[:minInt(len(s), maxSchemeLength+3)], "://")
+		if i < 1 || !isScheme(s[:i]) {
+			return 0
+		}
+		start = i + 3
+	}
+	return spanEnd(s, start, isURLEnd)
+}
+
+// emailLength returns the length of the email address at the start of s, or
+// 0 if there isn't one. The domain must contain a dot.
+func emailLength(s string) int {
+	at := strings.IndexByte(s[:minInt(len(s), maxLocalPartLength+1)], '@')
+	if at < 1 || s[0] == '.' {
+		return 0
+	}
+	for i := 0; i < at; i++ {
+		if !isLocalPartByte(s[i]) {
+			return 0
+		}
+	}
+
+	end, dot := at+1, -1
+	for end < len(s) {
+		r, size := utf8.DecodeRuneInString(s[end:])
+		if r == '.' && s[end-1] != '.' && s[end-1] != '@' {
+			dot = end
+		} else if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' {
+			break
+		}
+		end += size
+	}
+	// A full stop ending a sentence isn't part of the domain
+	if end > 0 && s[end-1] == '.' {
+		end--
+		dot = strings.LastIndexByte(s[:end], '.')
+	}
+	if dot <= at+1 || dot == end-1 {
+		return 0
+	}
+	return end
+}
+
+// isLocalPartByte reports whether c can appear in the part of an email
+// address before the @.
+func isLocalPartByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte(".!#$%&*+=?^_{|}~-", c) >= 0
+}
+
+// pathLength returns the length of the file path at the start of s, or 0 if
+// there isn't one.
+func pathLength(s string) int {
+	start := 0
+	switch {
+	case strings.HasPrefix(s, "/"):
+		start = 1
+	case strings.HasPrefix(s, "~/"), strings.HasPrefix(s, "./"):
+		start = 2
+	case strings.HasPrefix(s, "../"):
+		start = 3
+	case len(s) >= 3 && (s[0]|0x20) >= 'a' && (s[0]|0x20) <= 'z' && s[1] == ':' && (s[2] == '\\' || s[2] == '/'):
+		start = 3
+	default:
+		// A relative path, where the first name is followed by a slash
+		i := strings.IndexByte(s[:minInt(len(s), maxNameLength+1)], '/')
+		if i < 1 || strings.IndexFunc(s[:i], isPathEnd) >= 0 || !isPathName(s[:i]) {
+			return 0
+		}
+		start = i + 1
+	}
+	return spanEnd(s, start, isPathEnd)
+}
+
+// isPathName reports whether s can be the name at the start of a relative
+// path, which excludes names like "http:" that start URLs.
+func isPathName(s string) bool {
+	return !strings.HasSuffix(s, ":")
+}
+
+// spanEnd returns the length of the span at the start of s that ends
+// before the first rune that end reports true for, without any trailing
+// punctuation or unbalanced closing brackets. It returns 0 if nothing is left
+// after the first start bytes, which only start the span.
+func spanEnd(s string, start int, end func(rune) bool) int {
+	n := len(s)
+	if i := strings.IndexFunc(s[start:], end); i >= 0 {
+		n = start + i
+	}
+
+	// How many more of each kind of bracket are closed than opened
+	var unbalanced [3]int
+	for i := 0; i < n; i++ {
+		if k := strings.IndexByte("([{", s[i]); k >= 0 {
+			unbalanced[k]--
+		} else if k := strings.IndexByte(")]}", s[i]); k >= 0 {
+			unbalanced[k]++
+		}
+	}
+	for ; n > start; n-- {
+		c := s[n-1]
+		if k := strings.IndexByte(")]}", c); k >= 0 && unbalanced[k] > 0 {
+			unbalanced[k]--
+		} else if strings.IndexByte(".,:;!?'\"", c) < 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// isURLEnd reports whether r can't appear in a URL written in text.
+func isURLEnd(r rune) bool {
+	return r < ' ' || unicode.IsSpace(r) || strings.ContainsRune("\"<>`", r)
+}
+
+// isPathEnd reports whether r can't appear in a path written in text.
+func isPathEnd(r rune) bool {
+	return r < ' ' || unicode.IsSpace(r) || strings.ContainsRune("\"'`<>|,;", r)
+}
+
+// minInt returns the smaller of a and b.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
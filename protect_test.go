@@ -0,0 +1,56 @@
+package wrap
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestProtectedLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  ProtectOptions
+		expected int
+	}{
+		{"disabled", "https://example.com", ProtectOptions{}, 0},
+		{"url", "https://example.com/a-b c", ProtectOptions{URLs: true}, 23},
+		{"url with www", "www.example.com/a-b", ProtectOptions{URLs: true}, 19},
+		{"url with query", "http://x.io/?a=b&c=d-e", ProtectOptions{URLs: true}, 22},
+		{"url full stop", "https://example.com/a.", ProtectOptions{URLs: true}, 21},
+		{"url in brackets", "https://example.com/a)", ProtectOptions{URLs: true}, 21},
+		{"url with brackets", "https://example.com/a_(b))", ProtectOptions{URLs: true}, 25},
+		{"url before quote", "https://example.com\"", ProtectOptions{URLs: true}, 19},
+		{"scheme only", "https://", ProtectOptions{URLs: true}, 0},
+		{"not a scheme", "1http://example.com", ProtectOptions{URLs: true}, 0},
+		{"email", "first-last@example.com", ProtectOptions{Emails: true}, 22},
+		{"email full stop", "a.b@example.co.uk.", ProtectOptions{Emails: true}, 17},
+		{"email without dot", "user@localhost", ProtectOptions{Emails: true}, 0},
+		{"email without local part", "@example.com", ProtectOptions{Emails: true}, 0},
+		{"email with empty label", "a@.example.com", ProtectOptions{Emails: true}, 0},
+		{"absolute path", "/usr/local/bin, then", ProtectOptions{Paths: true}, 14},
+		{"home path", "~/.config/wrap", ProtectOptions{Paths: true}, 14},
+		{"parent path", "../wrap-tool", ProtectOptions{Paths: true}, 12},
+		{"windows path", `C:\Program Files`, ProtectOptions{Paths: true}, 10},
+		{"relative path", "cmd/wrap/main.go.", ProtectOptions{Paths: true}, 16},
+		{"slash", "/ alone", ProtectOptions{Paths: true}, 0},
+		{"not a path", "word", ProtectOptions{Paths: true}, 0},
+		{"url isn't a path", "https://example.com", ProtectOptions{Paths: true}, 0},
+		{"code", "`a b` c", ProtectOptions{Code: true}, 5},
+		{"unclosed code", "`a b c", ProtectOptions{Code: true}, 1},
+		{"unclosed code in a longer run", "``a`", ProtectOptions{Code: true}, 2},
+		{"pattern", "v1.2.3-rc1 ok", ProtectOptions{Pattern: regexp.MustCompile(`v\d+\.\d+\.\d+(-\w+)?`)}, 10},
+		{"pattern not at start", "see v1.2.3", ProtectOptions{Pattern: regexp.MustCompile(`v\d+\.\d+\.\d+`)}, 0},
+		{"empty match", "abc", ProtectOptions{Pattern: regexp.MustCompile(`x*`)}, 0},
+		{"longest alternative", "b-c d-e", ProtectOptions{Pattern: regexp.MustCompile(`b-c|b-c d-e`)}, 7},
+		{"longest", "www.example.com/a", ProtectOptions{URLs: true, Paths: true}, 17},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newWrapping(Wrapper{Protect: tt.options})
+			if got := w.protectedLength(tt.input); got != tt.expected {
+				t.Errorf("protectedLength(%q) = %d, want %d", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package wrap
+
+import (
+	"sort"
+	"strings"
+)
+
+// TabOptions sets where tab stops are, so that a tab advances to the next
+// one instead of counting as a single character. Columns are counted from 0
+// at the start of the output line, including OutputLinePrefix and any other
+// prefix, so tabs line up in the output whatever's written before them.
+//
+// The greedy algorithm measures every tab from the column it ends up at. The
+// other algorithms choose all the lines of a paragraph together, before the
+// column of a tab part way through a line is known, so they measure each tab
+// at its widest. Lines never exceed the limit either way, and tabs are always
+// expanded from their actual columns.
+type TabOptions struct {
+	// Width is the distance between tab stops, which are at every multiple
+	// of Width after any Stops. Tabs aren't expanded if Width is less than 1
+	// and there are no Stops.
+	// Default: 0
+	Width int
+
+	// Stops sets the columns of tab stops, as created by NewTabStops. After
+	// the last of them, tab stops are every Width columns, or every column if
+	// Width is less than 1.
+	// Default: nil
+	Stops *TabStops
+
+	// Keep can be set to true to write tab characters in the output, instead
+	// of the spaces they expand to. They're still measured in the same way,
+	// so a terminal with the same tab stops shows lines within the limit.
+	// Text aligned by Alignment is measured as if it were aligned left.
+	// Default: false
+	Keep bool
+}
+
+// TabStops is a list of tab stop columns. It's referred to by a pointer, so
+// that TabOptions, and so Wrapper, can be compared with ==.
+type TabStops struct {
+	columns []int
+}
+
+// NewTabStops returns tab stops at the given columns, which may be in any
+// order. Negative and repeated columns are ignored.
+func NewTabStops(columns ...int) *TabStops {
+	stops := make([]int, 0, len(columns))
+	for _, col := range columns {
+		if col >= 0 {
+			stops = append(stops, col)
+		}
+	}
+	sort.Ints(stops)
+	n := 0
+	for i, col := range stops {
+		if i == 0 || col != stops[n-1] {
+			stops[n] = col
+			n++
+		}
+	}
+	return &TabStops{columns: stops[:n]}
+}
+
+// list returns the columns of the tab stops, or nil if t is nil.
+func (t *TabStops) list() []int {
+	if t == nil {
+		return nil
+	}
+	return t.columns
+}
+
+// enabled reports whether tabs are expanded.
+func (o TabOptions) enabled() bool {
+	return o.Width > 0 || len(o.Stops.list()) > 0
+}
+
+// advance returns the number of columns a tab at column col advances by.
+func (o TabOptions) advance(col int) int {
+	for _, stop := range o.Stops.list() {
+		if stop > col {
+			return stop - col
+		}
+	}
+	if o.Width < 1 {
+		return 1
+	}
+	return o.Width - col%o.Width
+}
+
+// widest returns the most columns a tab can advance by.
+func (o TabOptions) widest() int {
+	n, prev := 1, 0
+	for _, stop := range o.Stops.list() {
+		if stop-prev > n {
+			n = stop - prev
+		}
+		prev = stop
+	}
+	if o.Width > n {
+		n = o.Width
+	}
+	return n
+}
+
+// unknownColumn is the column of text that isn't measured from the start of
+// a line, where tabs are measured at their widest.
+const unknownColumn = -1
+
+// expandsTabs reports whether s has any tabs that w expands.
+func (w *wrapping) expandsTabs(s string) bool {
+	return w.Tabs.enabled() && strings.IndexByte(s, '\t') >= 0
+}
+
+// advance returns the width of the cluster, which starts width columns into
+// the text being measured.
+func (w *wrapping) advance(cluster string, width int) int {
+	if cluster == "\t" && w.Tabs.enabled() {
+		return w.tabWidth(width)
+	}
+	return w.clusterWidth(cluster)
+}
+
+// tabWidth returns the width of a tab that starts width columns into the text
+// being measured.
+func (w *wrapping) tabWidth(width int) int {
+	if w.column < 0 {
+		return w.Tabs.widest()
+	}
+	return w.Tabs.advance(w.column + width)
+}
+
+// startLine sets w up to measure text at the start of a line, after its
+// prefixes. first is set for the first line of a paragraph.
+func (w *wrapping) startLine(first bool) {
+	if w.Tabs.enabled() {
+		w.column = w.lineColumn(first)
+	}
+}
+
+// lineColumn returns the output column that the text of a line starts at,
+// after OutputLinePrefix and then FirstLinePrefix or ContinuationPrefix.
+// first is set for the first line of a paragraph.
+func (w *wrapping) lineColumn(first bool) int {
+	prefix := w.ContinuationPrefix
+	if first {
+		prefix = w.FirstLinePrefix
+	}
+	return w.startWidth(w.OutputLinePrefix + prefix)
+}
+
+// startWidth returns the width of s written at the start of a line, leaving
+// the column that other text is measured from as it was.
+func (w *wrapping) startWidth(s string) int {
+	col := w.column
+	w.column = 0
+	width := w.stringWidth(s)
+	w.column = col
+	return width
+}
+
+// expandTabs returns s with every tab replaced by the spaces it advances
+// over.
+func (w *wrapping) expandTabs(s string) string {
+	if !w.expandsTabs(s) {
+		return s
+	}
+	var sb strings.Builder
+	width := 0
+	for s != "" {
+		n := w.nextCluster(s)
+		if s[:n] == "\t" {
+			tab := w.tabWidth(width)
+			sb.WriteString(w.spaces(tab))
+			width += tab
+		} else {
+			sb.WriteString(s[:n])
+			width += w.clusterWidth(s[:n])
+		}
+		s = s[n:]
+	}
+	return sb.String()
+}
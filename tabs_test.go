@@ -0,0 +1,44 @@
+package wrap
+
+import "testing"
+
+func TestTabOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options TabOptions
+		col     int
+		advance int
+		widest  int
+	}{
+		{"width", TabOptions{Width: 8}, 3, 5, 8},
+		{"width at stop", TabOptions{Width: 8}, 8, 8, 8},
+		{"stops", TabOptions{Stops: NewTabStops(4, 10)}, 4, 6, 6},
+		{"after stops", TabOptions{Stops: NewTabStops(4, 10)}, 10, 1, 6},
+		{"width after stops", TabOptions{Width: 4, Stops: NewTabStops(2)}, 3, 1, 4},
+		{"wide stop", TabOptions{Width: 4, Stops: NewTabStops(2, 12)}, 2, 10, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.options.advance(tt.col); got != tt.advance {
+				t.Errorf("advance(%d) = %d, want %d", tt.col, got, tt.advance)
+			}
+			if got := tt.options.widest(); got != tt.widest {
+				t.Errorf("widest() = %d, want %d", got, tt.widest)
+			}
+		})
+	}
+}
+
+func TestNewTabStops(t *testing.T) {
+	got := NewTabStops(10, -1, 4, 10, 0).list()
+	want := []int{0, 4, 10}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package wrap
+
+import "strings"
+
+// limitLines returns the lines of an input line that fit within MaxLines,
+// given the number of lines before them, and reports whether any were
+// dropped. more is set if there's more input after them, in which case the
+// last line allowed is truncated even if all the lines fit. first is set if
+// the lines start a paragraph.
+func (w *wrapping) limitLines(lines []Line, count, limit int, first, more bool) ([]Line, bool) {
+	keep := w.MaxLines - count
+	if w.MaxLines < 1 || len(lines) < keep || len(lines) == keep && !more {
+		return lines, false
+	}
+
+	lines = lines[:keep]
+	last := &lines[keep-1]
+	// Ellipsis replaces the hyphen of a hyphenated line
+	text := last.Text
+	if last.Break == BreakHyphen {
+		text = strings.TrimSuffix(text, w.Hyphenation.Hyphen)
+	}
+	w.startLine(first && keep == 1)
+	lineLimit := w.lineLimit(limit, first && keep == 1)
+	n := w.ellipsisIndex(text, lineLimit)
+	last.Text = text[:n] + w.ellipsis(lineLimit)
+	last.End = last.Start + n
+	last.Break = BreakTruncated
+	return lines, true
+}
+
+// ellipsisIndex returns the length of the text of s to keep before Ellipsis
+// so that the line fits within limit. The text is broken at the last
+// breakpoint that leaves enough room, or cut if there isn't one.
+func (w *wrapping) ellipsisIndex(s string, limit int) int {
+	s = trimTrailingSpaces(s)
+	room := limit - w.stringWidth(w.Ellipsis)
+	if limit < 1 || w.stringWidth(s) <= room {
+		return len(s)
+	}
+	if room < 1 {
+		return 0
+	}
+
+	if end, _, _, reason := w.greedyBreak(s, room); end > 0 && reason != BreakOverflow {
+		return len(trimTrailingSpaces(s[:end]))
+	}
+	// Nothing fits before a breakpoint, so cut the text
+	i := w.widthIndex(s, room)
+	if w.stringWidth(s[:i]) > room {
+		return 0
+	}
+	return i
+}
+
+// ellipsis returns Ellipsis, shortened to fit within limit if it doesn't.
+func (w *wrapping) ellipsis(limit int) string {
+	if limit < 1 {
+		return w.Ellipsis
+	}
+	return w.Ellipsis[:w.headIndex(w.Ellipsis, limit)]
+}
+
+// Truncate is shorthand for declaring a new default Wrapper calling its
+// Truncate method.
+func Truncate(s string, limit int) string {
+	return NewWrapper().Truncate(s, limit)
+}
+
+// Truncate shortens s to fit within limit, measured in the same way as Wrap,
+// by replacing part of it with Ellipsis as selected by TruncateMode. Grapheme
+// clusters and escape sequences are never split, and any escape sequences
+// that are removed are written after Ellipsis so that styles still apply. If
+// s already fits, or limit is less than 1, s is returned unchanged, apart from
+// expanding any tabs as set by Tabs. If Ellipsis doesn't fit within limit
+// either, it's shortened to fit instead.
+func (w Wrapper) Truncate(s string, limit int) string {
+	ww := newWrapping(w)
+	return ww.truncate(s, limit)
+}
+
+// truncate is Truncate for the wrapping of a single call.
+func (w *wrapping) truncate(s string, limit int) string {
+	// Styles can only be kept if escape sequences are recognised
+	if w.ReapplyStyles {
+		w.EscapeSequences = true
+	}
+	if w.Tabs.enabled() && !w.Tabs.Keep {
+		s = w.expandTabs(s)
+	}
+	if limit < 1 || w.stringWidth(s) <= limit {
+		return s
+	}
+	// Kept tabs move along with the text after Ellipsis
+	if w.Tabs.enabled() {
+		w.column = unknownColumn
+	}
+	room := limit - w.stringWidth(w.Ellipsis)
+	if room < 0 {
+		return w.ellipsis(limit)
+	}
+
+	// i and j are the start and end of the text to remove. Protected spans
+	// are only kept whole when moving them to breakpoints.
+	cw := *w
+	cw.Protect = ProtectOptions{}
+	i, j := 0, len(s)
+	switch w.TruncateMode {
+	case TruncateStart:
+		j = cw.tailIndex(s, room)
+	case TruncateMiddle:
+		i = cw.headIndex(s, (room+1)/2)
+		j = i + cw.tailIndex(s[i:], room-cw.stringWidth(s[:i]))
+	default:
+		i = cw.headIndex(s, room)
+	}
+	if w.TruncateAtBreakpoints {
+		i, j = w.breakHead(s, i), w.breakTail(s, i, j)
+	}
+	return s[:i] + w.Ellipsis + w.escapes(s[i:j]) + s[j:]
+}
+
+// headIndex returns the end of the longest run of whole clusters at the start
+// of s that fits within limit.
+func (w *wrapping) headIndex(s string, limit int) int {
+	width := 0
+	for i := 0; i < len(s); {
+		n := w.nextCluster(s[i:])
+		width += w.advance(s[i:i+n], width)
+		if width > limit {
+			return i
+		}
+		i += n
+	}
+	return len(s)
+}
+
+// tailIndex returns the start of the longest run of whole clusters at the end
+// of s that fits within limit.
+func (w *wrapping) tailIndex(s string, limit int) int {
+	total := w.stringWidth(s)
+	width := total
+	for i := 0; i < len(s); {
+		if width <= limit {
+			return i
+		}
+		n := w.nextCluster(s[i:])
+		width -= w.advance(s[i:i+n], total-width)
+		i += n
+	}
+	return len(s)
+}
+
+// breakHead moves the end i of the text kept at the start of s back to the
+// nearest breakpoint, before any spaces, unless that would leave no text.
+func (w *wrapping) breakHead(s string, i int) int {
+	if i == 0 || i == len(s) {
+		return i
+	}
+	end := i
+	if start, next := w.clusterAround(s, i); start != i || !w.canBreakAt(s[i:next], s[next:]) {
+		if _, end = w.lastBreakpoint(s, i); end < 0 {
+			return i
+		}
+	}
+	if k := len(trimTrailingSpaces(s[:end])); k > 0 {
+		return k
+	}
+	return i
+}
+
+// breakTail moves the start j of the text kept at the end of s forward to the
+// nearest breakpoint after i, after any spaces, unless that would leave no
+// text.
+func (w *wrapping) breakTail(s string, i, j int) int {
+	if j == len(s) || j == i {
+		return j
+	}
+	start := j
+	if _, end := w.lastBreakpoint(s[i:], j-i); end < 0 || i+end != j {
+		// Skip the rest of any protected span that j is inside
+		from := j
+		if k, next := w.clusterAround(s, j); k != j {
+			from = next
+		}
+		if start, _ = w.nextBreakpoint(s[from:]); start < 0 {
+			return j
+		}
+		start += from
+	}
+	for start < len(s) && s[start:start+w.nextCluster(s[start:])] == " " {
+		start++
+	}
+	if start < len(s) {
+		return start
+	}
+	return j
+}
+
+// clusterAround returns the start and end of the cluster in s that the byte
+// index i is within, which starts before i if i is inside a protected span.
+func (w *wrapping) clusterAround(s string, i int) (int, int) {
+	if !w.Protect.enabled() {
+		return i, i + w.nextCluster(s[i:])
+	}
+	for k := 0; k < len(s); {
+		n := w.nextCluster(s[k:])
+		if k+n > i {
+			return k, k + n
+		}
+		k += n
+	}
+	return len(s), len(s)
+}
+
+// escapes returns the escape sequences in s, if EscapeSequences is set.
+func (w *wrapping) escapes(s string) string {
+	if !w.EscapeSequences {
+		return ""
+	}
+	var sb strings.Builder
+	for s != "" {
+		n := w.nextCluster(s)
+		if w.isEscape(s[:n]) {
+			sb.WriteString(s[:n])
+		}
+		s = s[n:]
+	}
+	return sb.String()
+}
@@ -0,0 +1,211 @@
+package wrap
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Unwrap is shorthand for declaring a new default Wrapper calling its Unwrap method
+func Unwrap(s string) string {
+	return NewWrapper().Unwrap(s)
+}
+
+// Reflow is shorthand for declaring a new default Wrapper calling its Reflow method
+func Reflow(s string, limit int) string {
+	return NewWrapper().Reflow(s, limit)
+}
+
+// Reflow rewraps text that may already have been wrapped, by unwrapping it
+// before wrapping it at the given length. Reflowing text that's already been
+// reflowed gives the same result as reflowing the original text, whatever
+// length it was reflowed at before, as long as CutLongWords isn't set and no
+// hyphen is followed by a space other than the suspended hyphens described by
+// Unwrap.
+func (w Wrapper) Reflow(s string, limit int) string {
+	if w.Newline == "" {
+		w.Newline = defaultNewline
+	}
+	// Wrap adds back the trailing newline
+	return w.Wrap(strings.TrimSuffix(w.Unwrap(s), w.Newline), limit)
+}
+
+// Unwrap joins hard-wrapped lines back into paragraphs, each written on a
+// single line. TrimInputPrefix and TrimInputSuffix are removed from every
+// line, and FirstLinePrefix and ContinuationPrefix are removed from the first
+// and following lines of each paragraph.
+//
+// Paragraphs are separated by blank lines, which are kept, and a new one
+// starts wherever a line's indent doesn't line up with the start of the
+// paragraph's text. When PreserveIndent is set, the text starts after any
+// list markers, and a line that starts with a marker always starts a new
+// paragraph, unless it repeats the quote markers of a quoted paragraph, as
+// Wrap writes them.
+//
+// Lines are joined with a single space, except after a word ending with a
+// breakpoint that isn't a space, like a hyphen, or between wide East Asian
+// characters. A hyphen followed by "and", "or", "nor", "to" or "&" on the
+// next line is taken to be suspended, as in "pre- and post-war", and keeps
+// its space. Any other space after a hyphen is lost if a line was broken
+// there, as it can't be told apart from a word broken at its hyphen, so
+// "pre- longlong" wrapped after the hyphen is unwrapped as "pre-longlong".
+// Runs of spaces are collapsed into one.
+func (w Wrapper) Unwrap(s string) string {
+	if w.Newline == "" {
+		w.Newline = defaultNewline
+	}
+	ww := newWrapping(w)
+
+	lines := strings.Split(s, w.Newline)
+	trailing := len(lines) > 1 && lines[len(lines)-1] == ""
+	if trailing {
+		lines = lines[:len(lines)-1]
+	}
+
+	var out []string
+	var head string
+	// quote is what continuation lines of a quoted paragraph start with,
+	// repeating its quote markers.
+	var quote string
+	var text strings.Builder
+	// width is the width of the current paragraph's indent and markers, or
+	// -1 when there isn't a paragraph.
+	width := -1
+	endParagraph := func() {
+		if width >= 0 {
+			out = append(out, head+collapseSpaces(text.String()))
+			text.Reset()
+			width = -1
+		}
+	}
+
+	for _, line := range lines {
+		if w.TrimInputPrefix != "" && strings.TrimRight(line, indentSpace) == strings.TrimRight(w.TrimInputPrefix, indentSpace) {
+			// The prefixes of a blank line may have lost their trailing spaces
+			line = ""
+		}
+		line = strings.TrimPrefix(line, w.TrimInputPrefix)
+		line = strings.TrimSuffix(line, w.TrimInputSuffix)
+		line = strings.TrimRight(line, indentSpace)
+		if line == strings.TrimRight(w.FirstLinePrefix, indentSpace) {
+			endParagraph()
+			out = append(out, "")
+			continue
+		}
+
+		if width >= 0 && strings.HasPrefix(line, w.ContinuationPrefix+quote) {
+			rest := line[len(w.ContinuationPrefix)+len(quote):]
+			prefix, indent := ww.paragraphIndent(rest)
+			if prefix == indent && prefix < len(rest) && ww.stringWidth(quote+rest[:indent]) == width {
+				rest = rest[indent:]
+				text.WriteString(ww.joinSeparator(text.String(), rest))
+				text.WriteString(rest)
+				continue
+			}
+		}
+
+		endParagraph()
+		line = strings.TrimPrefix(line, w.FirstLinePrefix)
+		prefix, indent := ww.paragraphIndent(line)
+		head = line[:prefix]
+		width = ww.stringWidth(head)
+		quote = ""
+		if cont := ww.continuationIndent(line[indent:prefix]); strings.Contains(cont, ">") {
+			quote = line[:indent] + cont
+		}
+		text.WriteString(line[prefix:])
+	}
+	endParagraph()
+
+	result := strings.Join(out, w.Newline)
+	if trailing {
+		result += w.Newline
+	}
+	return result
+}
+
+// paragraphIndent returns the length of the indent at the start of s, and
+// any list markers and the spaces after them when PreserveIndent is set,
+// along with the length of just the leading indent.
+func (w *wrapping) paragraphIndent(s string) (prefix, indent int) {
+	if w.PreserveIndent {
+		return splitIndent(s)
+	}
+	indent = len(s) - len(strings.TrimLeft(s, indentSpace))
+	return indent, indent
+}
+
+// joinSeparator returns the text to write between the end of a paragraph,
+// prev, and the next line of it, next.
+func (w *wrapping) joinSeparator(prev, next string) string {
+	if prev == "" {
+		return ""
+	}
+	last, _ := utf8.DecodeLastRuneInString(prev)
+	first, _ := utf8.DecodeRuneInString(next)
+	if isWideText(last) && isWideText(first) {
+		return ""
+	}
+
+	// A line broken after a hyphen kept it, but one broken at a space
+	// following a hyphen, or after a lone hyphen, needs a space. So does a
+	// suspended hyphen, like the one in "pre- and post-war", which is only
+	// told apart from a word broken after a hyphen by what follows it.
+	trimmed := strings.TrimRightFunc(prev, w.isJoiningBreakpoint)
+	if len(trimmed) < len(prev) {
+		r, _ := utf8.DecodeLastRuneInString(trimmed)
+		if trimmed != "" && !unicode.IsSpace(r) && !startsWithConjunction(next) {
+			return ""
+		}
+	}
+	return " "
+}
+
+// suspendingConjunctions are the words that follow a suspended hyphen.
+var suspendingConjunctions = []string{"and", "or", "nor", "to", "&"}
+
+// startsWithConjunction reports whether s starts with one of the
+// suspendingConjunctions as a word on its own.
+func startsWithConjunction(s string) bool {
+	for _, word := range suspendingConjunctions {
+		if strings.HasPrefix(s, word) && (len(s) == len(word) || s[len(word)] == ' ') {
+			return true
+		}
+	}
+	return false
+}
+
+// isJoiningBreakpoint reports whether a line may have been broken after r
+// without a space.
+func (w *wrapping) isJoiningBreakpoint(r rune) bool {
+	if unicode.IsSpace(r) {
+		return false
+	}
+	if w.UnicodeLineBreaking {
+		class := lineBreakClass(r)
+		return class == lbHY || class == lbBA || class == lbB2
+	}
+	return strings.ContainsRune(w.Breakpoints, r)
+}
+
+// isWideText reports whether r is a wide East Asian character, which are
+// written without spaces between words, unlike Korean.
+func isWideText(r rune) bool {
+	return displayWidth(r, 1) == 2 && !isHangul(lineBreakClass(r))
+}
+
+// collapseSpaces replaces each run of spaces in s with a single space.
+func collapseSpaces(s string) string {
+	if !strings.Contains(s, "  ") {
+		return s
+	}
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == ' ' && i > 0 && s[i-1] == ' ' {
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
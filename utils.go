@@ -1,9 +1,20 @@
 package wrap
 
-import "unicode/utf8"
+import (
+	"strings"
+	"unicode/utf8"
+)
 
 // isASCII returns true if the string contains only ASCII characters.
 func isASCII(s string) bool {
+	// Check eight bytes at a time, as this is on the hot path of wrapping
+	for ; len(s) >= 8; s = s[8:] {
+		word := uint64(s[0]) | uint64(s[1])<<8 | uint64(s[2])<<16 | uint64(s[3])<<24 |
+			uint64(s[4])<<32 | uint64(s[5])<<40 | uint64(s[6])<<48 | uint64(s[7])<<56
+		if word&0x8080808080808080 != 0 {
+			return false
+		}
+	}
 	for i := 0; i < len(s); i++ {
 		if s[i] >= utf8.RuneSelf {
 			return false
@@ -12,6 +23,21 @@ func isASCII(s string) bool {
 	return true
 }
 
+// isSimple returns true if every byte of s is a grapheme cluster on its own,
+// which is the case for ASCII without any CRLF pairs or escape sequences.
+func isSimple(s string) bool {
+	// Short strings are quicker to check in a single pass
+	if len(s) < 16 {
+		for i := 0; i < len(s); i++ {
+			if c := s[i]; c >= utf8.RuneSelf || c == '\x1b' || c == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+				return false
+			}
+		}
+		return true
+	}
+	return isASCII(s) && !strings.Contains(s, "\r\n") && strings.IndexByte(s, '\x1b') < 0
+}
+
 // runeIndexToByte returns the byte index for a given rune index in s.
 // If runeIndex exceeds the string length, returns len(s).
 func runeIndexToByte(s string, runeIndex int) int {
@@ -55,3 +81,18 @@ func runeIndexToByteWithShortCheck(s string, runeIndex int) int {
 	}
 	return byteIndex
 }
+
+// trimTrailingSpaces returns s without trailing spaces or zero width spaces,
+// keeping one that forms a grapheme cluster with a preceding prepended
+// character.
+func trimTrailingSpaces(s string) string {
+	trimmed := strings.TrimRight(s, " "+zeroWidthSpace)
+	if len(trimmed) < len(s) {
+		r, _ := utf8.DecodeLastRuneInString(trimmed)
+		if graphemeBreakProperty(r) == gcbPrepend {
+			_, n := utf8.DecodeRuneInString(s[len(trimmed):])
+			return s[:len(trimmed)+n]
+		}
+	}
+	return trimmed
+}
@@ -0,0 +1,182 @@
+package wrap
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Measurer measures the width of text in units of its own choosing, so that
+// text set in a proportional font can be wrapped to a limit in pixels or
+// points. Widths are integers, so fractional advances are given in a
+// fixed-point unit, such as 26.6 fixed-point pixels or thousandths of a
+// point, by scaling them and rounding.
+//
+// When a Wrapper has a Measurer, the limit and every other width it's given,
+// such as Widows.MinWidth and the columns of Tabs, are in the same units, and
+// penalties are in the same units squared. Alignment and justification pad
+// lines with as many spaces, or Fill runes, as fit.
+type Measurer interface {
+	// Width returns the advance of s, which is a single grapheme cluster.
+	// The width of longer text is the sum of the widths of its clusters, so
+	// any kerning between them isn't included.
+	Width(s string) int
+}
+
+// MeasurerFunc returns a Measurer that measures text with f. It's referred to
+// by a pointer, so that a Wrapper using it can still be compared with ==, and
+// each call returns a distinct Measurer.
+func MeasurerFunc(f func(s string) int) Measurer {
+	return &funcMeasurer{f}
+}
+
+// funcMeasurer is the Measurer returned by MeasurerFunc.
+type funcMeasurer struct {
+	f func(s string) int
+}
+
+// Width returns f(s).
+func (m *funcMeasurer) Width(s string) int {
+	return m.f(s)
+}
+
+// runeRange is an inclusive range of code points.
+type runeRange struct {
+	lo, hi rune
+}
+
+// inRanges reports whether r falls within one of the sorted ranges.
+func inRanges(r rune, ranges []runeRange) bool {
+	lo, hi := 0, len(ranges)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		switch {
+		case r < ranges[mid].lo:
+			hi = mid
+		case r > ranges[mid].hi:
+			lo = mid + 1
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// displayWidth returns the number of terminal columns occupied by r,
+// following the Unicode East Asian Width property. Ambiguous characters
+// occupy the given number of columns.
+func displayWidth(r rune, ambiguous int) int {
+	switch {
+	case r < 0x20 || (r >= 0x7F && r < 0xA0):
+		// C0 and C1 control characters
+		return 0
+	case r < 0x7F:
+		return 1
+	case r >= 0x1160 && r <= 0x11FF, r >= 0xD7B0 && r <= 0xD7FF:
+		// Hangul medial vowels and final consonants combine with the
+		// preceding initial consonant.
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case inRanges(r, eastAsianWide):
+		return 2
+	case ambiguous == 2 && inRanges(r, eastAsianAmbiguous):
+		return 2
+	}
+	return 1
+}
+
+// isInvisible reports whether r is a format character that never takes up a
+// column, even when every other rune counts as one: a soft hyphen, zero width
+// space or word joiner.
+func isInvisible(r rune) bool {
+	return r == 0xAD || r == 0x200B || r == 0x2060 || r == 0xFEFF
+}
+
+// runeWidth returns the number of runes in s that aren't invisible.
+func runeWidth(s string) int {
+	n := utf8.RuneCountInString(s)
+	if n == len(s) {
+		// Only ASCII has as many runes as bytes
+		return n
+	}
+	for _, r := range s {
+		if isInvisible(r) {
+			n--
+		}
+	}
+	return n
+}
+
+// countsRunes reports whether w measures the width of text by counting its
+// runes, so that every rune is at most one unit wide.
+func (w *wrapping) countsRunes() bool {
+	return !w.DisplayWidth && w.Measurer == nil
+}
+
+// stringWidth returns the width of s as measured by w.
+func (w *wrapping) stringWidth(s string) int {
+	if w.countsRunes() && !w.EscapeSequences && !w.expandsTabs(s) {
+		return runeWidth(s)
+	}
+	width := 0
+	for s != "" {
+		n := w.nextCluster(s)
+		width += w.advance(s[:n], width)
+		s = s[n:]
+	}
+	return width
+}
+
+// widthIndex returns the byte index of the end of the longest prefix of s
+// whose width does not exceed limit, without splitting grapheme clusters.
+// At least one cluster is always consumed so callers cutting s can make
+// progress.
+func (w *wrapping) widthIndex(s string, limit int) int {
+	if w.countsRunes() && !w.protecting() && !w.expandsTabs(s) {
+		if i := runeIndexToByte(s, limit); isSimple(s[:clusterEnd(s, i)]) {
+			return i
+		}
+	}
+	width := 0
+	for i := 0; i < len(s); {
+		n := w.nextCluster(s[i:])
+		width += w.advance(s[i:i+n], width)
+		if width > limit && i > 0 {
+			return i
+		}
+		// A protected span wider than the limit is cut if it has to be
+		if width > limit && w.Protect.enabled() {
+			if k := w.cutIndex(s, n, limit); k > 0 {
+				return k
+			}
+		}
+		i += n
+	}
+	return len(s)
+}
+
+// limitIndex returns the byte index of the end of the window of grapheme
+// clusters in s that start at or before column limit, or -1 if the whole of s
+// fits within limit.
+func (w *wrapping) limitIndex(s string, limit int) int {
+	if w.countsRunes() && !w.EscapeSequences && !w.protecting() && !w.expandsTabs(s) {
+		// Every rune counts, so the total width is just the rune count
+		i := runeIndexToByteWithShortCheck(s, limit+1)
+		if i < 0 || isSimple(s[:clusterEnd(s, i)]) {
+			return i
+		}
+	}
+	width := 0
+	for i := 0; i < len(s); {
+		if width > limit {
+			return i
+		}
+		n := w.nextCluster(s[i:])
+		width += w.advance(s[i:i+n], width)
+		i += n
+	}
+	if width <= limit {
+		return -1
+	}
+	return len(s)
+}
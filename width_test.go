@@ -0,0 +1,112 @@
+package wrap
+
+import "testing"
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     rune
+		ambiguous int
+		expected  int
+	}{
+		{"ASCII letter", 'a', 1, 1},
+		{"control character", '\x1b', 1, 0},
+		{"C1 control character", '\u0085', 1, 0},
+		{"CJK ideograph", '日', 1, 2},
+		{"hiragana", 'あ', 1, 2},
+		{"hangul syllable", '한', 1, 2},
+		{"hangul medial vowel", 'ᅡ', 1, 0},
+		{"fullwidth letter", 'Ａ', 1, 2},
+		{"halfwidth katakana", 'ｱ', 1, 1},
+		{"combining acute accent", '́', 1, 0},
+		{"enclosing mark", '⃝', 1, 0},
+		{"zero width joiner", '‍', 1, 0},
+		{"emoji presentation", '😀', 1, 2},
+		{"ambiguous as narrow", '°', 1, 1},
+		{"ambiguous as wide", '°', 2, 2},
+		{"neutral with ambiguous wide", 'ä', 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := displayWidth(tt.input, tt.ambiguous); got != tt.expected {
+				t.Errorf("displayWidth(%q, %d) = %d, want %d", tt.input, tt.ambiguous, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRuneWidth(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int
+	}{
+		{"ASCII", "hello", 5},
+		{"multibyte", "日本語", 3},
+		{"soft hyphen", "hy\u00adphen", 6},
+		{"zero width space", "a\u200bb", 2},
+		{"word joiner", "e-\u2060mail", 6},
+		{"zero width no-break space", "\ufeffa", 1},
+		{"no-break space", "10\u00a0km", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runeWidth(tt.input); got != tt.expected {
+				t.Errorf("runeWidth(%q) = %d, want %d", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_WidthIndex(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		displayWidth bool
+		limit        int
+		expected     int
+	}{
+		{"runes", "日本語", false, 2, 6},
+		{"display width", "日本語", true, 4, 6},
+		{"display width straddling limit", "日本語", true, 3, 3},
+		{"display width consumes at least one rune", "日本語", true, 1, 3},
+		{"display width includes trailing zero width", "ae\u0301b", true, 2, 4},
+		{"display width fits", "ab", true, 5, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrapping{Wrapper: NewWrapper()}
+			w.DisplayWidth = tt.displayWidth
+			if got := w.widthIndex(tt.input, tt.limit); got != tt.expected {
+				t.Errorf("widthIndex(%q, %d) = %d, want %d", tt.input, tt.limit, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_LimitIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		expected int
+	}{
+		{"fits", "日本", 4, -1},
+		{"window ends after rune starting at limit", "日本語", 4, 9},
+		{"window ends after rune straddling limit", "日本語", 3, 6},
+		{"last rune straddles limit", "日本", 3, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrapping{Wrapper: NewWrapper()}
+			w.DisplayWidth = true
+			if got := w.limitIndex(tt.input, tt.limit); got != tt.expected {
+				t.Errorf("limitIndex(%q, %d) = %d, want %d", tt.input, tt.limit, got, tt.expected)
+			}
+		})
+	}
+}
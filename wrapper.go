@@ -1,6 +1,7 @@
 package wrap
 
 import (
+	"regexp"
 	"strings"
 	"unicode/utf8"
 )
@@ -8,12 +9,82 @@ import (
 const (
 	defaultBreakpoints = " -"
 	defaultNewline     = "\n"
+	defaultEllipsis    = "…"
+
+	// zeroWidthSpace is an invisible breakpoint.
+	zeroWidthSpace = "\u200B"
+)
+
+// Algorithm selects how a Wrapper chooses where to break lines.
+type Algorithm int
+
+const (
+	// AlgorithmGreedy fills each line with as many words as fit before moving
+	// on to the next. It's the fastest, and can wrap text as it's written.
+	AlgorithmGreedy Algorithm = iota
+
+	// AlgorithmMinimumRaggedness minimises the sum of the squares of the
+	// space left at the end of each line, producing more evenly sized lines.
+	AlgorithmMinimumRaggedness
+
+	// AlgorithmKnuthPlass uses the total-fit algorithm of Knuth and Plass, as
+	// used by TeX. It models spaces as stretchable glue and hyphens as
+	// penalties, and finds the breaks that give the best spacing overall, as
+	// configured by KnuthPlassOptions.
+	AlgorithmKnuthPlass
+
+	// AlgorithmBalanced uses as few lines as the greedy algorithm, but moves
+	// words between them so that every line, including the last, is about the
+	// same width, like text-wrap: balance in CSS. It suits short text such as
+	// headings and labels.
+	AlgorithmBalanced
+)
+
+// Alignment selects how a Wrapper positions text within each line.
+type Alignment int
+
+const (
+	// AlignLeft leaves lines ragged on the right.
+	AlignLeft Alignment = iota
+
+	// AlignJustify widens the spaces between words so that each line fills
+	// the limit. The last line of each paragraph is left ragged unless
+	// JustifyLastLine is set, and lines without spaces are left as they are.
+	AlignJustify
+
+	// AlignCenter centers each line within the limit, with any odd column of
+	// padding on the right.
+	AlignCenter
+
+	// AlignRight aligns each line to the right of the limit.
+	AlignRight
+)
+
+// TruncateMode selects which part of a string Truncate removes.
+type TruncateMode int
+
+const (
+	// TruncateEnd keeps the start of the string, and replaces the end with
+	// Ellipsis.
+	TruncateEnd TruncateMode = iota
+
+	// TruncateStart keeps the end of the string, and replaces the start with
+	// Ellipsis.
+	TruncateStart
+
+	// TruncateMiddle keeps both ends of the string, and replaces the middle
+	// with Ellipsis.
+	TruncateMiddle
 )
 
 // Wrapper contains settings for customisable word-wrapping.
 type Wrapper struct {
 	// Breakpoints defines which characters should be able to break a line.
 	// By default, this follows the usual English rules of spaces, and hyphens.
+	// A zero width space (U+200B) can always break a line, while no-break
+	// spaces (U+00A0, U+2007 and U+202F) and word joiners (U+2060 and U+FEFF)
+	// never do, even if listed here, and a breakpoint followed by a word
+	// joiner doesn't either. Soft hyphens (U+00AD) are handled by Hyphenation.
 	// Default: " -"
 	Breakpoints string
 
@@ -30,6 +101,18 @@ type Wrapper struct {
 	// Default: ""
 	OutputLineSuffix string
 
+	// FirstLinePrefix is written after OutputLinePrefix on the first output
+	// line of each input line, such as "- " for a list item.
+	// Default: ""
+	FirstLinePrefix string
+
+	// ContinuationPrefix is written after OutputLinePrefix on every other
+	// output line, such as "  " to align them with a list item's text. Each of
+	// FirstLinePrefix and ContinuationPrefix only counts against the limit of
+	// the lines it's written on.
+	// Default: ""
+	ContinuationPrefix string
+
 	// LimitIncludesPrefixSuffix can be set to false if you don't want prefixes
 	// and suffixes to be included in the length limits.
 	// Default: true
@@ -53,10 +136,163 @@ type Wrapper struct {
 	// CutLongWords will cause a hard-wrap in the middle of a word if the word's length exceeds the given limit.
 	CutLongWords bool
 
+	// Hyphenation allows words to be broken at hyphenation points, such as
+	// those found by HyphenatorEnUS, adding a hyphen at the end of the line.
+	// A word is only hyphenated if it doesn't fit on the line otherwise, and
+	// before it would be cut by CutLongWords. Words containing soft hyphens
+	// (U+00AD) are always hyphenated at them instead, even without a
+	// Hyphenator, and a soft hyphen is replaced by the hyphen when a word is
+	// broken there. Otherwise, soft hyphens are kept but have no width.
+	// Default: no hyphenation, as described by HyphenationOptions
+	Hyphenation HyphenationOptions
+
+	// Protect selects spans of text, such as URLs and code, that lines are
+	// never broken within, even at Breakpoints or hyphenation points.
+	// Default: nothing is protected, as described by ProtectOptions
+	Protect ProtectOptions
+
+	// Tabs expands tab characters to the next tab stop, measured from the
+	// start of each output line, instead of counting each as a single
+	// character.
+	// Default: tabs aren't expanded, as described by TabOptions
+	Tabs TabOptions
+
+	// UnicodeLineBreaking can be set to true to find break opportunities with
+	// the Unicode Line Breaking Algorithm (UAX #14) instead of Breakpoints.
+	// This allows breaks between ideographs and after dashes, while keeping
+	// punctuation such as closing quotes and brackets with the preceding word.
+	// Mandatory breaks, such as U+2028 LINE SEPARATOR, always end a line.
+	// Default: false
+	UnicodeLineBreaking bool
+
 	// MinimumRaggedness enables optimal-fit line breaking which produces
 	// more visually balanced paragraphs. This is more expensive than the
 	// default greedy algorithm but produces better visual results.
+	// It's equivalent to setting Algorithm to AlgorithmMinimumRaggedness.
 	MinimumRaggedness bool
+
+	// Algorithm selects how line breaks are chosen.
+	// Default: AlgorithmGreedy
+	Algorithm Algorithm
+
+	// KnuthPlass tunes AlgorithmKnuthPlass.
+	// Default: TeX's parameters, as described by KnuthPlassOptions
+	KnuthPlass KnuthPlassOptions
+
+	// Widows discourages AlgorithmMinimumRaggedness and AlgorithmBalanced from
+	// ending a paragraph with a very short line.
+	// Default: no penalties
+	Widows WidowOptions
+
+	// PreserveIndent can be set to true to keep the indent and any list or
+	// quote markers, such as "-", "*", "1." or ">", at the start of each input
+	// line. They're written after FirstLinePrefix on the first output line,
+	// and continuation lines are indented to line up with the text after them,
+	// repeating any quote markers. Lines aren't broken where the text after the
+	// break would be taken for a marker.
+	// Default: false
+	PreserveIndent bool
+
+	// Alignment selects how text is positioned within each line. Any padding
+	// is added between OutputLinePrefix and OutputLineSuffix, and lines that
+	// don't fill the limit are also padded on the right when there's a suffix,
+	// so that suffixes line up.
+	// Default: AlignLeft
+	Alignment Alignment
+
+	// Fill is the rune used to pad lines when aligning them. Spaces are used
+	// for any columns too narrow to fit another Fill, and instead of runes
+	// with no width.
+	// Default: ' '
+	Fill rune
+
+	// JustifyLastLine can be set to true to also justify the last line of
+	// each paragraph, and lines that end with a mandatory break, when
+	// Alignment is AlignJustify.
+	// Default: false
+	JustifyLastLine bool
+
+	// DisplayWidth can be set to true to measure lines in terminal columns
+	// rather than runes. Wide and fullwidth East Asian characters count as two
+	// columns, and zero-width characters such as combining marks count as none.
+	// Soft hyphens, zero width spaces and word joiners never count.
+	// Default: false
+	DisplayWidth bool
+
+	// AmbiguousWidth is the number of columns occupied by East Asian Ambiguous
+	// characters when DisplayWidth is enabled. Values other than 2 are treated
+	// as 1, which suits most non-CJK terminals.
+	// Default: 1
+	AmbiguousWidth int
+
+	// Measurer can be set to measure text in units such as pixels or points,
+	// rather than runes or columns, for text set in a proportional font. The
+	// limit and other widths are in the same units, as described by Measurer.
+	// It takes the place of DisplayWidth and AmbiguousWidth.
+	// Default: nil
+	Measurer Measurer
+
+	// EscapeSequences can be set to true to treat ANSI escape sequences, such
+	// as colors set by "\x1b[31m", as zero-width and never break inside them.
+	// Default: false
+	EscapeSequences bool
+
+	// ReapplyStyles can be set to true to close any active ANSI styles at the
+	// end of each line and reapply them after OutputLinePrefix on the next,
+	// so that colors don't bleed into prefixes and suffixes. This implies
+	// EscapeSequences.
+	// Default: false
+	ReapplyStyles bool
+
+	// MaxLines limits the output to this many lines. If there's more text
+	// after the last of them, it's dropped and the last line ends with
+	// Ellipsis instead, breaking it earlier if needed so that Ellipsis fits
+	// within the limit. There's no maximum if MaxLines is less than 1.
+	// Default: 0
+	MaxLines int
+
+	// Ellipsis is written at the end of the last line when text is dropped
+	// because of MaxLines, and in place of the text removed by Truncate. It's
+	// shortened to fit if it's wider than the limit.
+	// Default: "…"
+	Ellipsis string
+
+	// TruncateMode selects which part of a string Truncate removes.
+	// Default: TruncateEnd
+	TruncateMode TruncateMode
+
+	// TruncateAtBreakpoints can be set to true for Truncate to remove whole
+	// words where it can, by cutting the string next to one of the
+	// Breakpoints. Spaces next to Ellipsis are removed too. Spans selected by
+	// Protect are removed or kept as a whole where they can be.
+	// Default: false
+	TruncateAtBreakpoints bool
+}
+
+// wrapping holds the settings of a Wrapper for the duration of a single call,
+// along with the state that changes as text is wrapped, so that a Wrapper is
+// only ever configuration.
+type wrapping struct {
+	Wrapper
+
+	// markdown is set by WrapMarkdown to keep Markdown inline code spans,
+	// link destinations and autolinks in single unbreakable clusters, and to
+	// stop lines from starting with syntax that would start a block.
+	markdown bool
+
+	// column is the output column that the text being measured starts at,
+	// which sets how far any tabs in it advance. It's unknownColumn when that
+	// isn't known.
+	column int
+
+	// pattern is Protect.Pattern anchored to the start of the text, so that
+	// it's only compiled once per call.
+	pattern *regexp.Regexp
+}
+
+// newWrapping returns a wrapping for a single call with the settings of w.
+func newWrapping(w Wrapper) wrapping {
+	return wrapping{Wrapper: w, pattern: anchored(w.Protect.Pattern)}
 }
 
 // NewWrapper returns a new instance of a Wrapper initialised with defaults.
@@ -65,6 +301,15 @@ func NewWrapper() Wrapper {
 		Breakpoints:               defaultBreakpoints,
 		Newline:                   defaultNewline,
 		LimitIncludesPrefixSuffix: true,
+		AmbiguousWidth:            1,
+		KnuthPlass:                defaultKnuthPlass,
+		Hyphenation: HyphenationOptions{
+			MinLeft:  defaultHyphenMinLeft,
+			MinRight: defaultHyphenMinRight,
+			Hyphen:   defaultHyphen,
+		},
+		Fill:     ' ',
+		Ellipsis: defaultEllipsis,
 	}
 }
 
@@ -76,17 +321,24 @@ func Wrap(s string, limit int) string {
 // Wrap will wrap one or more lines of text at the given length.
 // If limit is less than 1, the string remains unwrapped.
 func (w Wrapper) Wrap(s string, limit int) string {
-	// Empty newline would cause infinite loop, use default
-	if w.Newline == "" {
-		w.Newline = defaultNewline
-	}
+	ww := newWrapping(w)
+	limit = ww.setup(limit)
+	wrapped, _ := ww.wrap(s, limit)
+	return wrapped
+}
 
-	// Subtract the length of the prefix and suffix from the limit
-	// so we don't break length limits when using them.
-	if w.LimitIncludesPrefixSuffix {
-		limit -= utf8.RuneCountInString(w.OutputLinePrefix) + utf8.RuneCountInString(w.OutputLineSuffix)
-	}
+// WrapTruncated wraps s in the same way as Wrap, and also reports whether any
+// text was dropped because of MaxLines.
+func (w Wrapper) WrapTruncated(s string, limit int) (string, bool) {
+	ww := newWrapping(w)
+	limit = ww.setup(limit)
+	return ww.wrap(s, limit)
+}
 
+// wrap is WrapTruncated once setup has been called. Lines wrapped by the
+// greedy algorithm are written straight to the result as they're found,
+// without building a Line for each of them first.
+func (w *wrapping) wrap(s string, limit int) (string, bool) {
 	var sb strings.Builder
 	growLimit := limit
 	if growLimit < 1 {
@@ -94,102 +346,741 @@ func (w Wrapper) Wrap(s string, limit int) string {
 	}
 	sb.Grow(len(s) + len(s)/growLimit*len(w.Newline))
 
-	for {
-		idx := strings.Index(s, w.Newline)
-		var str string
-		if idx < 0 {
-			str = s
-		} else {
-			str = s[:idx]
+	truncated := false
+	if w.Algorithm != AlgorithmGreedy || w.MaxLines > 0 {
+		first := true
+		w.eachLine(s, limit, func(lines []Line) {
+			if !first {
+				sb.WriteString(w.Newline)
+			}
+			first = false
+			w.writeLines(&sb, lines)
+			truncated = lines[len(lines)-1].Break == BreakTruncated
+		})
+	} else {
+		w.writeGreedy(&sb, s, limit)
+	}
+	if !w.StripTrailingNewline {
+		sb.WriteString(w.Newline)
+	}
+	return sb.String(), truncated
+}
+
+// writeGreedy writes each input line of s to sb as wrapped by the greedy
+// algorithm, separated by Newline, in the same way as eachLine and
+// writeLines.
+func (w *wrapping) writeGreedy(sb *strings.Builder, s string, limit int) {
+	var st styleState
+	plain := w.plain()
+	for offset := 0; ; {
+		end := len(s)
+		idx := strings.Index(s[offset:], w.Newline)
+		if idx >= 0 {
+			end = offset + idx
+		}
+
+		str, start := s[offset:end], offset
+		if strings.HasPrefix(str, w.TrimInputPrefix) {
+			str, start = str[len(w.TrimInputPrefix):], start+len(w.TrimInputPrefix)
 		}
-		str = strings.TrimPrefix(str, w.TrimInputPrefix)
 		str = strings.TrimSuffix(str, w.TrimInputSuffix)
-		w.lineBuilder(&sb, str, limit)
-		if idx < 0 {
-			if !w.StripTrailingNewline {
-				sb.WriteString(w.Newline)
+		lw := w
+		if w.PreserveIndent {
+			iw, rest := w.withIndent(str)
+			lw = &iw
+			start += len(str) - len(rest)
+			str = rest
+		}
+		if offset > 0 {
+			sb.WriteString(w.Newline)
+		}
+		// Simple text is written without building a Line, for as long as it
+		// lasts
+		first, done := true, false
+		for plain && !done {
+			next, ok := lw.writePlainLine(sb, str, limit, first)
+			if !ok {
+				break
+			}
+			if next < 0 {
+				done = true
+			} else {
+				str, start, first = str[next:], start+next, false
 			}
-			break
 		}
-		sb.WriteString(w.Newline)
-		s = s[idx+len(w.Newline):]
+		if !done {
+			lw.greedyLines(str, start, limit, first, func(line Line) {
+				if !first {
+					sb.WriteString(w.Newline)
+				}
+				if idx >= 0 && line.Break == BreakEnd {
+					line.Break = BreakNewline
+				}
+				lw.writeLine(sb, line, limit, first, &st)
+				first = false
+			})
+		}
+		if idx < 0 {
+			return
+		}
+		offset = end + len(w.Newline)
 	}
+}
 
-	return sb.String()
+// plain reports whether lines can be written by writePlainLine, as they're
+// measured in runes, broken only at Breakpoints and written with nothing but
+// prefixes and a suffix around them.
+func (w *wrapping) plain() bool {
+	return w.Algorithm == AlgorithmGreedy && w.MaxLines < 1 && w.Alignment == AlignLeft &&
+		w.countsRunes() && !w.EscapeSequences && !w.protecting() && !w.UnicodeLineBreaking &&
+		w.Hyphenation.Hyphenator == nil && !w.Tabs.enabled() && !w.PreserveIndent
 }
 
-// lineBuilder writes a single wrapped line to the builder.
-func (w Wrapper) lineBuilder(sb *strings.Builder, s string, limit int) {
-	// Trim leading breakpoints to avoid empty or whitespace-only lines
-	s = strings.TrimLeft(s, w.Breakpoints)
+// writePlainLine writes the first line that the rest of an input line s
+// wraps into to sb, preceded by Newline unless first is set, in the same way
+// as greedyLines and writeLine. It returns the start of the rest of s, or -1
+// if s was written in full. It writes nothing and returns false if the text
+// it examines to find the break isn't simple, as it's measured and broken
+// byte by byte.
+func (w *wrapping) writePlainLine(sb *strings.Builder, s string, limit int, first bool) (int, bool) {
+	// Trim leading breakpoints, unless they may be part of a longer cluster
+	trimmed := strings.TrimLeft(s, w.Breakpoints)
+	k := len(s) - len(trimmed)
+	from := k
+	if from > 0 {
+		from--
+	}
+	if !isSimple(s[from:clusterEnd(s, k)]) {
+		return 0, false
+	}
+	s = trimmed
 
-	// Use optimal algorithm if MinimumRaggedness is enabled
-	if w.MinimumRaggedness && limit > 0 {
-		w.lineBuilderOptimal(sb, s, limit)
-		return
+	end, next := len(s), -1
+	if limit := w.lineLimit(limit, first); limit > 0 && len(s) > limit {
+		var ok bool
+		if end, next, ok = w.plainBreak(s, limit); !ok {
+			return 0, false
+		}
 	}
 
-	// Fast path: if byte length is less than limit, rune count must also be less
-	if limit < 1 || len(s) < limit+1 {
+	text, prefix := s, w.ContinuationPrefix
+	if next >= 0 {
+		text = strings.TrimRight(s[:end], " ")
+	}
+	if first {
+		prefix = w.FirstLinePrefix
+	} else {
+		sb.WriteString(w.Newline)
+	}
+	// Most lines have no prefixes or suffix
+	if w.OutputLinePrefix != "" || prefix != "" {
 		sb.WriteString(w.OutputLinePrefix)
-		sb.WriteString(s)
+		sb.WriteString(prefix)
+	}
+	sb.WriteString(text)
+	if w.OutputLineSuffix != "" {
 		sb.WriteString(w.OutputLineSuffix)
-		return
 	}
+	if next < 0 {
+		return -1, true
+	}
+	return k + next, true
+}
 
-	// Convert rune limit to byte index for slicing (also checks rune count)
-	limitByteIndex := runeIndexToByteWithShortCheck(s, limit+1)
-	if limitByteIndex < 0 {
-		// String is shorter than limit in runes
-		sb.WriteString(w.OutputLinePrefix)
-		sb.WriteString(s)
-		sb.WriteString(w.OutputLineSuffix)
-		return
+// plainBreak returns the end of the first line of s, which is wider than
+// limit, and the start of the remainder, as greedyBreak does. Both are -1 if
+// s can't be broken. It returns false if the text it examines isn't simple.
+func (w *wrapping) plainBreak(s string, limit int) (int, int, bool) {
+	if !isSimple(s[:clusterEnd(s, limit+1)]) {
+		return 0, 0, false
+	}
+	i := strings.LastIndexAny(s[:limit+1], w.Breakpoints)
+	if i < 0 {
+		if w.CutLongWords {
+			return limit, limit, true
+		}
+		i = strings.IndexAny(s, w.Breakpoints)
+		if i < 0 {
+			return -1, -1, isSimple(s)
+		}
+		if !isSimple(s[:clusterEnd(s, i+1)]) {
+			return 0, 0, false
+		}
+	}
+	// Non-space breakpoints (like hyphen) should stay on the line
+	if s[i] != ' ' {
+		return i + 1, i + 1, true
+	}
+	return i, i + 1, true
+}
+
+// setup fills in defaults for any unusable settings, and returns the limit
+// available to the content of each line.
+func (w *wrapping) setup(limit int) int {
+	// Empty newline would cause infinite loop, use default
+	if w.Newline == "" {
+		w.Newline = defaultNewline
 	}
 
-	// Find the index of the last breakpoint within the limit.
-	i := strings.LastIndexAny(s[:limitByteIndex], w.Breakpoints)
+	if w.MinimumRaggedness && w.Algorithm == AlgorithmGreedy {
+		w.Algorithm = AlgorithmMinimumRaggedness
+	}
+	if w.KnuthPlass == (KnuthPlassOptions{}) {
+		w.KnuthPlass = defaultKnuthPlass
+	}
+
+	if w.Hyphenation.Hyphen == "" {
+		w.Hyphenation.Hyphen = defaultHyphen
+	}
 
-	breakpointWidth := 1
-	keepBreakpoint := false
+	if w.Fill == 0 {
+		w.Fill = ' '
+	}
+
+	// Styles can only be tracked if escape sequences are recognised
+	if w.ReapplyStyles {
+		w.EscapeSequences = true
+	}
+
+	// Subtract the length of the prefix and suffix from the limit
+	// so we don't break length limits when using them.
+	if w.LimitIncludesPrefixSuffix {
+		limit -= w.stringWidth(w.OutputLinePrefix) + w.stringWidth(w.OutputLineSuffix)
+	}
+
+	return limit
+}
+
+// lineBuilder appends the wrapped lines of a single input line s to lines,
+// without any prefixes or suffixes, which are added by render. start is the
+// offset of s in the input, and first is set if s starts a paragraph. The
+// last line ends with BreakEnd.
+func (w *wrapping) lineBuilder(lines []Line, s string, start, limit int, first bool) []Line {
+	// Use an optimal algorithm if one is selected
+	if w.Algorithm != AlgorithmGreedy && w.lineLimit(limit, first) > 0 && w.lineLimit(limit, false) > 0 {
+		trimmed := w.trimBreakpoints(s)
+		return w.lineBuilderOptimal(lines, trimmed, start+len(s)-len(trimmed), limit, first)
+	}
+	w.greedyLines(s, start, limit, first, func(line Line) {
+		lines = append(lines, line)
+	})
+	return lines
+}
+
+// greedyLines passes each line of s, as wrapped by the greedy algorithm, to
+// emit in turn, as described by lineBuilder.
+func (w *wrapping) greedyLines(s string, start, limit int, first bool, emit func(Line)) {
+	for ; ; first = false {
+		// Trim leading breakpoints to avoid empty or whitespace-only lines
+		trimmed := w.trimBreakpoints(s)
+		start += len(s) - len(trimmed)
+		s = trimmed
+
+		w.startLine(first)
+		end, next, _, reason := w.greedyBreak(s, w.lineLimit(limit, first))
+		if next < 0 {
+			emit(newLine(s, start, BreakEnd))
+			return
+		}
+		emit(w.brokenLine(trimTrailingSpaces(s[:end]), start, reason))
+
+		// The rest of a protected span that was cut is cut again, rather than
+		// broken at any breakpoints inside it.
+		if reason == BreakCut {
+			if n := w.protectedLength(s); n > next {
+				next = w.cutSpan(s, start, next, n, limit, emit)
+			}
+		}
+		s, start = s[next:], start+next
+	}
+}
+
+// lineLimit returns the limit available to the text of a line, after any
+// FirstLinePrefix or ContinuationPrefix. first is set for the first line of a
+// paragraph.
+func (w *wrapping) lineLimit(limit int, first bool) int {
+	if !w.LimitIncludesPrefixSuffix {
+		return limit
+	}
+	// Tabs in FirstLinePrefix or ContinuationPrefix advance from the end of
+	// OutputLinePrefix, which setup has already taken off the limit
+	if w.Tabs.enabled() {
+		return limit - w.lineColumn(first) + w.startWidth(w.OutputLinePrefix)
+	}
+	if first {
+		return limit - w.stringWidth(w.FirstLinePrefix)
+	}
+	return limit - w.stringWidth(w.ContinuationPrefix)
+}
+
+// greedyBreak returns the end of the first line of s as wrapped by the greedy
+// algorithm, and the start of the remainder. Both are -1 if s can't or
+// needn't be broken. The third value is the index up to which s was examined
+// to decide on the break, and the fourth is the reason for the break.
+func (w *wrapping) greedyBreak(s string, limit int) (int, int, int, BreakReason) {
+	end, next, seen, reason := w.greedyBreakWords(s, limit)
+	if limit < 1 || reason == BreakNewline {
+		return end, next, seen, reason
+	}
+	// The last word may still overflow when there's nowhere else to break
+	if next < 0 {
+		if !w.hyphenating(s) || w.stringWidth(s) <= limit {
+			return end, next, seen, reason
+		}
+		seen = len(s)
+	}
+
+	// Hyphenate the word that overflows the line, which is the first word
+	// unless the line was broken at a breakpoint. Only soft hyphens within
+	// what's been examined could keep the line within the limit.
+	from := 0
+	if reason == BreakBreakpoint {
+		from = next
+	}
+	if w.Hyphenation.Hyphenator == nil && !strings.Contains(s[from:seen], softHyphen) {
+		return end, next, seen, reason
+	}
+	to := len(s)
+	if w.UnicodeLineBreaking {
+		if i := strings.IndexByte(s[from:], ' '); i >= 0 {
+			to = from + i
+		}
+	} else if i, _ := w.nextBreakpoint(s[from:]); i >= 0 {
+		to = from + i
+	}
+	// Where the word can be hyphenated depends on all of it
+	if to > seen {
+		seen = to
+	}
+	room := limit - w.stringWidth(w.Hyphenation.Hyphen)
+	points := w.hyphenPoints(s[from:to])
+	for k := len(points) - 1; k >= 0; k-- {
+		i := from + points[k]
+		if w.stringWidth(s[:i]) > room {
+			continue
+		}
+		// A soft hyphen is replaced by the hyphen written at the break
+		next := i
+		if strings.HasPrefix(s[i:], softHyphen) {
+			next += len(softHyphen)
+		}
+		return i, next, seen, BreakHyphen
+	}
+	return end, next, seen, reason
+}
+
+// greedyBreakWords is greedyBreak without hyphenation.
+func (w *wrapping) greedyBreakWords(s string, limit int) (int, int, int, BreakReason) {
+	if limit < 1 {
+		return -1, -1, -1, BreakEnd
+	}
+	// Fast path: if byte length is less than limit, width must also be less,
+	// unless a Measurer measures runes as wider than one
+	if len(s) <= limit && w.Measurer == nil && !w.expandsTabs(s) && (!w.UnicodeLineBreaking || !strings.ContainsAny(s, lineEndChars)) {
+		return -1, -1, -1, BreakEnd
+	}
+	if w.UnicodeLineBreaking {
+		return w.unicodeBreak(s, limit)
+	}
+
+	// Fast path: plain text is broken at the last breakpoint byte that
+	// starts by column limit
+	if end := limit + 1; end <= len(s) && w.plainBreaks(s[:clusterEnd(s, end)]) {
+		if i := strings.LastIndexAny(s[:end], w.Breakpoints); i >= 0 {
+			if s[i] != ' ' {
+				return i + 1, i + 1, end, BreakBreakpoint
+			}
+			return i, i + 1, end, BreakBreakpoint
+		}
+	}
+
+	// Convert limit to byte index for slicing (also checks width)
+	limitByteIndex := w.limitIndex(s, limit)
+	if limitByteIndex < 0 {
+		// String is narrower than limit
+		return -1, -1, -1, BreakEnd
+	}
+
+	// Find the start and end of the last breakpoint within the limit.
+	i, j := w.lastBreakpoint(s, limitByteIndex)
+	reason := BreakBreakpoint
 
 	// Can't wrap within the limit
 	if i < 0 {
 		if w.CutLongWords {
-			// wrap at the limit (convert rune index to byte index)
-			i = runeIndexToByte(s, limit)
-			breakpointWidth = 0
-		} else {
-			// wrap at the next breakpoint instead
-			i = strings.IndexAny(s, w.Breakpoints)
-			// Nothing left to do!
-			if i < 0 {
-				sb.WriteString(w.OutputLinePrefix)
-				sb.WriteString(s)
-				sb.WriteString(w.OutputLineSuffix)
-				return
+			// wrap at the limit (convert width to byte index)
+			i = w.widthIndex(s, limit)
+			// A single character wider than the limit can't be cut any further
+			if i == len(s) {
+				return -1, -1, -1, BreakEnd
 			}
+			i = w.cutAwayFromSoftHyphen(s, i)
+			return i, i, limitByteIndex, BreakCut
+		}
+
+		// wrap at the next breakpoint instead
+		i, j = w.nextBreakpoint(s)
+		// Nothing left to do!
+		if i < 0 {
+			return -1, -1, -1, BreakEnd
 		}
+		reason = BreakOverflow
+	}
+
+	seen := limitByteIndex
+	if j > seen {
+		seen = j
 	}
 
 	// Non-space breakpoints (like hyphen) should stay on the line
-	if breakpointWidth > 0 && s[i] != ' ' {
-		keepBreakpoint = true
+	if s[i] != ' ' && s[i:j] != zeroWidthSpace {
+		return j, j, seen, reason
+	}
+	return i, j, seen, reason
+}
+
+// cutAwayFromSoftHyphen returns i, where a word at the start of s is cut to
+// fit on a line, moved back a grapheme cluster at a time until it isn't next
+// to a soft hyphen. Hyphenation breaks a word at a soft hyphen by writing the
+// hyphen, so a word is only cut there if the hyphen didn't fit, and the cut
+// would leave no sign of the break. The cut is left at i if there's nowhere
+// earlier to cut.
+func (w *wrapping) cutAwayFromSoftHyphen(s string, i int) int {
+	for j := i; j > 0; {
+		for strings.HasSuffix(s[:j], softHyphen) {
+			j -= len(softHyphen)
+		}
+		if j == 0 {
+			break
+		}
+		if !strings.HasPrefix(s[j:], softHyphen) {
+			return j
+		}
+		// Drop the cluster before the soft hyphen, unless it's the only one
+		k := w.widthIndex(s[:j], w.stringWidth(s[:j])-1)
+		if k == 0 || k >= j {
+			break
+		}
+		j = k
 	}
+	return i
+}
 
-	// Write this line and recurse
-	sb.WriteString(w.OutputLinePrefix)
-	lineContent := s[:i]
-	if keepBreakpoint {
-		lineContent = s[:i+1]
+// brokenLine returns a line for the text s, which starts at the given offset
+// in the input and ends for the given reason, adding the hyphen if it ends at
+// a hyphenation point.
+func (w *wrapping) brokenLine(s string, start int, reason BreakReason) Line {
+	line := newLine(s, start, reason)
+	if reason == BreakHyphen {
+		line.Text += w.Hyphenation.Hyphen
 	}
-	sb.WriteString(strings.TrimRight(lineContent, " "))
+	return line
+}
+
+// newLine returns a line for the text s, which starts at the given offset in
+// the input and ends for the given reason.
+func newLine(s string, start int, reason BreakReason) Line {
+	return Line{Text: s, Start: start, End: start + len(s), Break: reason}
+}
+
+// render justifies the text of each line as needed, and fills in its width
+// and the output prefixes and suffix that surround it. first is set if the
+// first line starts a paragraph.
+func (w *wrapping) render(lines []Line, limit int, first bool, st *styleState) {
+	for i := range lines {
+		w.renderLine(&lines[i], limit, first && i == 0, st)
+	}
+}
+
+// renderLine renders a single line, as described by render.
+func (w *wrapping) renderLine(line *Line, limit int, first bool, st *styleState) {
+	l := w.layout(line, limit, first, st)
+	line.Prefix = l.prefix + l.indent + w.fill(l.left) + l.active
+	line.Suffix = l.reset + w.fill(l.right) + w.OutputLineSuffix
+}
+
+// writeLine writes a single line to sb with its prefixes and suffix, in the
+// same way as renderLine, without building them as strings.
+func (w *wrapping) writeLine(sb *strings.Builder, line Line, limit int, first bool, st *styleState) {
+	l := w.layout(&line, limit, first, st)
+	sb.WriteString(l.prefix)
+	sb.WriteString(l.indent)
+	w.writeFill(sb, l.left)
+	sb.WriteString(l.active)
+	sb.WriteString(line.Text)
+	sb.WriteString(l.reset)
+	w.writeFill(sb, l.right)
 	sb.WriteString(w.OutputLineSuffix)
-	sb.WriteString(w.Newline)
+}
+
+// lineLayout is what's written around the text of a line.
+type lineLayout struct {
+	// prefix is OutputLinePrefix, and indent is FirstLinePrefix or
+	// ContinuationPrefix. Both are in prefix when tabs are expanded.
+	prefix, indent string
 
-	// Trim leading breakpoints from the next line to avoid leading whitespace
-	remainder := s[i+breakpointWidth:]
-	remainder = strings.TrimLeft(remainder, w.Breakpoints)
+	// left and right are the widths of the padding either side of the text.
+	left, right int
 
-	w.lineBuilder(sb, remainder, limit)
+	// active is written before the text to reapply its styles, and reset
+	// after it to close them.
+	active, reset string
+}
+
+// layout justifies the text of a line and expands its tabs as needed, fills
+// in its width, and returns what's written around it.
+func (w *wrapping) layout(line *Line, limit int, first bool, st *styleState) lineLayout {
+	limit = w.lineLimit(limit, first)
+	w.startLine(first)
+	if !w.Tabs.Keep {
+		line.Text = w.expandTabs(line.Text)
+	}
+	last := line.Break == BreakEnd || line.Break == BreakNewline || line.Break == BreakTruncated
+	if w.Alignment == AlignJustify && (!last || w.JustifyLastLine) {
+		line.Text = w.justify(line.Text, limit)
+	}
+	s := line.Text
+	line.Width = w.stringWidth(s)
+
+	// Split any space left on the line between the two sides
+	var l lineLayout
+	if extra := limit - line.Width; extra > 0 {
+		switch w.Alignment {
+		case AlignCenter:
+			l.left = extra / 2
+		case AlignRight:
+			l.left = extra
+		}
+		// Keep suffixes lined up when a line doesn't fill the limit
+		if w.Alignment != AlignLeft && w.OutputLineSuffix != "" {
+			l.right = extra - l.left
+		}
+	}
+
+	l.prefix, l.indent = w.OutputLinePrefix, w.ContinuationPrefix
+	if first {
+		l.indent = w.FirstLinePrefix
+	}
+	if w.ReapplyStyles {
+		l.active = st.active
+		st.update(s)
+		if st.active != "" {
+			l.reset = sgrReset
+		}
+	}
+	// Tabs in the prefixes are expanded from the start of the line
+	if !w.Tabs.Keep && w.expandsTabs(l.prefix+l.indent) {
+		w.column = 0
+		l.prefix, l.indent = w.expandTabs(l.prefix+l.indent), ""
+	}
+	return l
+}
+
+// fill returns width columns of padding, using the Fill rune as many times as
+// it fits and spaces for the rest.
+func (w *wrapping) fill(width int) string {
+	if width <= 0 {
+		return ""
+	}
+	var sb strings.Builder
+	w.writeFill(&sb, width)
+	return sb.String()
+}
+
+// writeFill writes the padding returned by fill to sb.
+func (w *wrapping) writeFill(sb *strings.Builder, width int) {
+	if width <= 0 {
+		return
+	}
+	if n := w.fillRuneWidth(); n > 0 {
+		for ; width >= n; width -= n {
+			sb.WriteRune(w.Fill)
+		}
+	}
+	if n := w.stringWidth(" "); n > 0 {
+		for ; width >= n; width -= n {
+			sb.WriteByte(' ')
+		}
+	}
+}
+
+// fillWidth returns the width of the padding that fill returns for width.
+func (w *wrapping) fillWidth(width int) int {
+	if width <= 0 {
+		return 0
+	}
+	filled := 0
+	if n := w.fillRuneWidth(); n > 0 {
+		filled = width / n * n
+	}
+	if n := w.stringWidth(" "); n > 0 {
+		filled += (width - filled) / n * n
+	}
+	return filled
+}
+
+// fillRuneWidth returns the width of the Fill rune.
+func (w *wrapping) fillRuneWidth() int {
+	// Converting from bytes doesn't allocate for a single byte rune
+	var buf [utf8.UTFMax]byte
+	return w.stringWidth(string(buf[:utf8.EncodeRune(buf[:], w.Fill)]))
+}
+
+// spaces returns as many spaces as fit within width.
+func (w *wrapping) spaces(width int) string {
+	n := w.stringWidth(" ")
+	if n < 1 || width < n {
+		return ""
+	}
+	return strings.Repeat(" ", width/n)
+}
+
+// isBreakpoint reports whether the grapheme cluster begins with one of the
+// Breakpoints characters, or is a zero width space. No-break spaces and word
+// joiners are never breakpoints.
+func (w *wrapping) isBreakpoint(cluster string) bool {
+	if w.isEscape(cluster) || w.protectedLength(cluster) > 0 {
+		return false
+	}
+	if cluster[0] < utf8.RuneSelf {
+		return strings.IndexByte(w.Breakpoints, cluster[0]) >= 0
+	}
+	r, _ := utf8.DecodeRuneInString(cluster)
+	if isGlue(r) {
+		return false
+	}
+	return r == 0x200B || strings.ContainsRune(w.Breakpoints, r)
+}
+
+// canBreakAt reports whether a line can be broken at the breakpoint cluster,
+// which is followed by rest. A word joiner after a breakpoint prevents the
+// break, as does syntax that would be read differently at the start of the
+// new line.
+func (w *wrapping) canBreakAt(cluster, rest string) bool {
+	return w.isBreakpoint(cluster) && !startsWithJoiner(rest) && !w.startsSyntax(rest)
+}
+
+// startsSyntax reports whether rest, the text after a break, would start a
+// Markdown block, or be taken for list or quote markers when PreserveIndent
+// is set, once the spaces at the start of the new line are trimmed.
+func (w *wrapping) startsSyntax(rest string) bool {
+	return w.markdown && startsBlock(rest) || w.PreserveIndent && startsListItem(rest)
+}
+
+// startsWithJoiner reports whether s starts with a word joiner.
+func startsWithJoiner(s string) bool {
+	return len(s) >= 3 && s[0] >= utf8.RuneSelf && (strings.HasPrefix(s, "\u2060") || strings.HasPrefix(s, "\uFEFF"))
+}
+
+// isGlue reports whether r joins the text either side of it, so that it's
+// never a breakpoint: a no-break space, figure space, narrow no-break space
+// or word joiner.
+func isGlue(r rune) bool {
+	return r == 0xA0 || r == 0x2007 || r == 0x202F || r == 0x2060 || r == 0xFEFF
+}
+
+// plainBreaks reports whether s can be measured and broken byte by byte, as
+// every byte of it is a grapheme cluster one column wide that can only be a
+// breakpoint if it's one of the Breakpoints. This is the case for plain
+// ASCII text when nothing is protected, tabs aren't expanded and list markers
+// aren't kept by PreserveIndent.
+func (w *wrapping) plainBreaks(s string) bool {
+	return w.countsRunes() && !w.EscapeSequences && !w.protecting() && !w.PreserveIndent && !w.expandsTabs(s) && isSimple(s)
+}
+
+// clusterEnd returns the index one byte past i in s, so that a cluster or
+// word joiner straddling i is seen, or len(s) if i is at or past the end.
+func clusterEnd(s string, i int) int {
+	if i >= len(s) {
+		return len(s)
+	}
+	return i + 1
+}
+
+// lastBreakpoint returns the start and end byte indexes of the last
+// breakpoint cluster that ends by end in s, or -1, -1 if there isn't one.
+func (w *wrapping) lastBreakpoint(s string, end int) (int, int) {
+	start, stop := -1, -1
+	for i := 0; i < end; {
+		n := w.nextCluster(s[i:])
+		if i+n <= end && w.canBreakAt(s[i:i+n], s[i+n:]) {
+			start, stop = i, i+n
+		}
+		i += n
+	}
+	return start, stop
+}
+
+// nextBreakpoint returns the start and end byte indexes of the first
+// breakpoint cluster in s, or -1, -1 if there isn't one.
+func (w *wrapping) nextBreakpoint(s string) (int, int) {
+	for i := 0; i < len(s); {
+		n := w.nextCluster(s[i:])
+		if w.canBreakAt(s[i:i+n], s[i+n:]) {
+			return i, i + n
+		}
+		i += n
+	}
+	return -1, -1
+}
+
+// trimBreakpoints returns s without any leading breakpoint clusters, or
+// without leading spaces when UnicodeLineBreaking is enabled.
+func (w *wrapping) trimBreakpoints(s string) string {
+	for s != "" {
+		n := w.nextCluster(s)
+		if w.UnicodeLineBreaking && s[:n] != " " {
+			break
+		}
+		if !w.UnicodeLineBreaking && !w.isBreakpoint(s[:n]) {
+			break
+		}
+		s = s[n:]
+	}
+	return s
+}
+
+// justify widens the runs of spaces between words in s so that it fills
+// limit, as far as whole spaces fit. The first runs are widened by one more
+// space than the rest if the extra spaces can't be shared evenly.
+func (w *wrapping) justify(s string, limit int) string {
+	extra := limit - w.stringWidth(s)
+	if n := w.stringWidth(" "); n > 1 {
+		extra /= n
+	}
+	if extra <= 0 {
+		return s
+	}
+
+	// Find the end of each run of spaces between words
+	var gaps []int
+	inWord := false
+	for i := 0; i < len(s); {
+		n := w.nextCluster(s[i:])
+		isSpace := s[i:i+n] == " "
+		if isSpace && inWord && i+n < len(s) && s[i+n] != ' ' {
+			gaps = append(gaps, i+n)
+		}
+		inWord = inWord || !isSpace
+		i += n
+	}
+	if len(gaps) == 0 {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s) + extra)
+	per, rem := extra/len(gaps), extra%len(gaps)
+	prev := 0
+	for i, gap := range gaps {
+		sb.WriteString(s[prev:gap])
+		n := per
+		if i < rem {
+			n++
+		}
+		sb.WriteString(strings.Repeat(" ", n))
+		prev = gap
+	}
+	sb.WriteString(s[prev:])
+	return sb.String()
 }
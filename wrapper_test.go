@@ -1,6 +1,8 @@
 package wrap_test
 
 import (
+	"fmt"
+	"regexp"
 	"strings"
 	"testing"
 	"unicode/utf8"
@@ -303,6 +305,7 @@ func TestWrapper_CutLongWordsFeatures(t *testing.T) {
 		{"cut single long word", "abcdefghij", 5, "abcde\nfghij"},
 		{"cut with normal words", "hi abcdefghij bye", 5, "hi\nabcde\nfghij\nbye"},
 		{"no cut needed", "hello world", 10, "hello\nworld"},
+		{"soft hyphen without room for the hyphen", "xyinter\u00adnational", 7, "xyinte\nr-\nnationa\nl"},
 	}
 
 	for _, tt := range tests {
@@ -317,6 +320,53 @@ func TestWrapper_CutLongWordsFeatures(t *testing.T) {
 	}
 }
 
+func TestWrapper_CutLongWordsFit(t *testing.T) {
+	inputs := []string{
+		"xyinter\u00adnational",
+		"an extra\u00adordinarily long\u00adwinded sentence",
+		"self-evident well-known state-of-the-art",
+	}
+	algorithms := map[string]wrap.Algorithm{
+		"greedy":             wrap.AlgorithmGreedy,
+		"minimum raggedness": wrap.AlgorithmMinimumRaggedness,
+		"knuth-plass":        wrap.AlgorithmKnuthPlass,
+		"balanced":           wrap.AlgorithmBalanced,
+	}
+
+	for name, algorithm := range algorithms {
+		t.Run(name, func(t *testing.T) {
+			for _, hyphenator := range []*wrap.Hyphenator{nil, wrap.HyphenatorEnUS()} {
+				for _, unicode := range []bool{false, true} {
+					w := wrap.NewWrapper()
+					w.Algorithm = algorithm
+					w.CutLongWords = true
+					w.Hyphenation.Hyphenator = hyphenator
+					w.UnicodeLineBreaking = unicode
+					for _, s := range inputs {
+						// The greedy algorithm lets a hyphen that's a breakpoint
+						// hang past the limit, as it always has
+						if algorithm == wrap.AlgorithmGreedy && strings.Contains(s, "-") {
+							continue
+						}
+						// A hyphen needs a column after at least one character
+						for limit := 2; limit <= 12; limit++ {
+							for _, line := range w.Lines(s, limit) {
+								if line.Width > limit {
+									t.Errorf("%q at %d: line %q is %d wide", s, limit, line.Text, line.Width)
+								}
+								// A word broken at a soft hyphen ends with the hyphen
+								if strings.HasSuffix(line.Text, "\u00ad") {
+									t.Errorf("%q at %d: line %q ends with a soft hyphen", s, limit, line.Text)
+								}
+							}
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
 func TestWrapper_CombinedFeatures(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -538,6 +588,36 @@ func TestWrapper_MinimumRaggedness(t *testing.T) {
 			limit:    15,
 			expected: "The quick brown\nfox jumps over\nthe lazy dog",
 		},
+		{
+			name:     "keeps hyphen at break",
+			input:    "We hold these truths to be self-evident, that all",
+			limit:    18,
+			expected: "We hold these\ntruths to be self-\nevident, that all",
+		},
+		{
+			name:     "space before a hyphen",
+			input:    "see ./p/q.go - it works",
+			limit:    9,
+			expected: "see\n./p/q.go\n- it\nworks",
+		},
+		{
+			name:     "runs of hyphens",
+			input:    "a -- b",
+			limit:    3,
+			expected: "a -\n- b",
+		},
+		{
+			name:     "overflowing words",
+			input:    "aaa bb cc dd eeee ffff",
+			limit:    7,
+			expected: "aaa\nbb cc\ndd eeee\nffff",
+		},
+		{
+			name:     "lines after an overflowing word",
+			input:    "aaaaaaaaa bb ccc",
+			limit:    5,
+			expected: "aaaaaaaaa\nbb ccc",
+		},
 	}
 
 	for _, tt := range tests {
@@ -625,3 +705,2653 @@ func TestWrapper_MinimumRaggednessUTF8(t *testing.T) {
 		t.Errorf("result is not valid UTF-8: %q", got)
 	}
 }
+
+func TestWrapper_DisplayWidth(t *testing.T) {
+	tests := []struct {
+		name              string
+		input             string
+		limit             int
+		cutLongWords      bool
+		minimumRaggedness bool
+		ambiguousWidth    int
+		prefix            string
+		expected          string
+	}{
+		{"wide characters", "日本 語テ スト", 5, false, false, 1, "", "日本\n語テ\nスト"},
+		{"wide characters fit", "日本 語", 7, false, false, 1, "", "日本 語"},
+		{"cut wide characters", "日本語テスト", 6, true, false, 1, "", "日本語\nテスト"},
+		{"cut wide character wider than limit", "日本", 1, true, false, 1, "", "日\n本"},
+		{"cut does not straddle limit", "a日本", 2, true, false, 1, "", "a\n日\n本"},
+		{"fullwidth forms", "ＡＢ ＣＤ", 4, false, false, 1, "", "ＡＢ\nＣＤ"},
+		{"combining marks are zero width", "café café", 9, false, false, 1, "", "café café"},
+		{"ambiguous narrow", "°°° °°°", 7, false, false, 1, "", "°°° °°°"},
+		{"ambiguous wide", "°°° °°°", 7, false, false, 2, "", "°°°\n°°°"},
+		{"wide prefix", "ab cd ef", 8, false, false, 1, "＃ ", "＃ ab cd\n＃ ef"},
+		{"minimum raggedness", "日本 語 テスト", 6, false, true, 1, "", "日本\n語\nテスト"},
+		{"minimum raggedness cut", "日本語テスト", 4, true, true, 1, "", "日本\n語テ\nスト"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			w.DisplayWidth = true
+			w.AmbiguousWidth = tt.ambiguousWidth
+			w.CutLongWords = tt.cutLongWords
+			w.MinimumRaggedness = tt.minimumRaggedness
+			w.OutputLinePrefix = tt.prefix
+			w.StripTrailingNewline = true
+			if got := w.Wrap(tt.input, tt.limit); got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_GraphemeClusters(t *testing.T) {
+	tests := []struct {
+		name              string
+		input             string
+		limit             int
+		displayWidth      bool
+		minimumRaggedness bool
+		expected          string
+	}{
+		{"combining marks stay with base", "e\u0301e\u0301e\u0301", 2, false, false, "e\u0301\ne\u0301\ne\u0301"},
+		{"flags are not split", "🇺🇸🇬🇧🇯🇵", 3, false, false, "🇺🇸\n🇬🇧\n🇯🇵"},
+		{"cluster wider than limit", "👨‍👩‍👧‍👦", 1, false, false, "👨‍👩‍👧‍👦"},
+		{"flags in display width", "🇺🇸🇬🇧🇯🇵", 4, true, false, "🇺🇸🇬🇧\n🇯🇵"},
+		{"family in display width", "👨‍👩‍👧‍👦👨‍👩‍👧‍👦", 2, true, false, "👨‍👩‍👧‍👦\n👨‍👩‍👧‍👦"},
+		{"hangul jamo", "\u1100\u1161\u11a8\u1100\u1161\u11a8", 2, true, false, "\u1100\u1161\u11a8\n\u1100\u1161\u11a8"},
+		{"minimum raggedness flags", "🇺🇸🇬🇧🇯🇵", 3, false, true, "🇺🇸\n🇬🇧\n🇯🇵"},
+		{"minimum raggedness combining marks", "e\u0301e\u0301e\u0301", 2, false, true, "e\u0301\ne\u0301\ne\u0301"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			w.CutLongWords = true
+			w.DisplayWidth = tt.displayWidth
+			w.MinimumRaggedness = tt.minimumRaggedness
+			w.StripTrailingNewline = true
+			if got := w.Wrap(tt.input, tt.limit); got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_MultibyteBreakpoints(t *testing.T) {
+	w := wrap.NewWrapper()
+	w.Breakpoints = " —"
+	w.StripTrailingNewline = true
+
+	got := w.Wrap("aaa—bbb—ccc", 4)
+	expected := "aaa—\nbbb—\nccc"
+	if got != expected {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+}
+
+func TestWrapper_EscapeSequences(t *testing.T) {
+	tests := []struct {
+		name              string
+		input             string
+		limit             int
+		cutLongWords      bool
+		displayWidth      bool
+		minimumRaggedness bool
+		reapplyStyles     bool
+		prefix            string
+		expected          string
+	}{
+		{
+			name:     "styles are zero width",
+			input:    "\x1b[31mhello\x1b[0m \x1b[32mworld\x1b[0m",
+			limit:    11,
+			expected: "\x1b[31mhello\x1b[0m \x1b[32mworld\x1b[0m",
+		},
+		{
+			name:     "break between styled words",
+			input:    "\x1b[31mhello\x1b[0m \x1b[32mworld\x1b[0m",
+			limit:    5,
+			expected: "\x1b[31mhello\x1b[0m\n\x1b[32mworld\x1b[0m",
+		},
+		{
+			name:         "cut long words around escapes",
+			input:        "\x1b[31mabcdefghij\x1b[0m",
+			limit:        5,
+			cutLongWords: true,
+			expected:     "\x1b[31mabcde\nfghij\x1b[0m",
+		},
+		{
+			name:     "hyperlink with hyphen is not broken",
+			input:    "\x1b]8;;http://a-b.c\x1b\\link\x1b]8;;\x1b\\ text",
+			limit:    4,
+			expected: "\x1b]8;;http://a-b.c\x1b\\link\x1b]8;;\x1b\\\ntext",
+		},
+		{
+			name:         "display width",
+			input:        "\x1b[1m日本\x1b[0m 語",
+			limit:        4,
+			displayWidth: true,
+			expected:     "\x1b[1m日本\x1b[0m\n語",
+		},
+		{
+			name:              "minimum raggedness",
+			input:             "\x1b[31ma b c d e f g h i j k l m n o p\x1b[0m",
+			limit:             9,
+			minimumRaggedness: true,
+			expected:          "\x1b[31ma b c d\ne f g h\ni j k l\nm n o p\x1b[0m",
+		},
+		{
+			name:          "reapply styles after prefix",
+			input:         "\x1b[31mhello world\x1b[0m",
+			limit:         8,
+			reapplyStyles: true,
+			prefix:        "// ",
+			expected:      "// \x1b[31mhello\x1b[0m\n// \x1b[31mworld\x1b[0m",
+		},
+		{
+			name:          "reapply nested styles",
+			input:         "\x1b[1mbold \x1b[31mred\x1b[0m plain",
+			limit:         5,
+			reapplyStyles: true,
+			expected:      "\x1b[1mbold\x1b[0m\n\x1b[1m\x1b[31mred\x1b[0m\nplain",
+		},
+		{
+			name:              "reapply styles with minimum raggedness",
+			input:             "\x1b[32maaa bbb ccc\x1b[0m",
+			limit:             5,
+			minimumRaggedness: true,
+			reapplyStyles:     true,
+			expected:          "\x1b[32maaa\x1b[0m\n\x1b[32mbbb\x1b[0m\n\x1b[32mccc\x1b[0m",
+		},
+		{
+			name:          "reapply styles across input lines",
+			input:         "\x1b[34mone\ntwo\x1b[0m",
+			limit:         80,
+			reapplyStyles: true,
+			expected:      "\x1b[34mone\x1b[0m\n\x1b[34mtwo\x1b[0m",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			w.EscapeSequences = true
+			w.CutLongWords = tt.cutLongWords
+			w.DisplayWidth = tt.displayWidth
+			w.MinimumRaggedness = tt.minimumRaggedness
+			w.ReapplyStyles = tt.reapplyStyles
+			w.OutputLinePrefix = tt.prefix
+			w.StripTrailingNewline = true
+			if got := w.Wrap(tt.input, tt.limit); got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_UnicodeLineBreaking(t *testing.T) {
+	tests := []struct {
+		name              string
+		input             string
+		limit             int
+		cutLongWords      bool
+		displayWidth      bool
+		minimumRaggedness bool
+		escapeSequences   bool
+		expected          string
+	}{
+		{
+			name:     "break after hyphen",
+			input:    "well-known fact",
+			limit:    6,
+			expected: "well-\nknown\nfact",
+		},
+		{
+			name:     "numeric range stays together",
+			input:    "pages 10-20",
+			limit:    9,
+			expected: "pages\n10-20",
+		},
+		{
+			name:     "closing punctuation stays with word",
+			input:    "price: $(12.50) each",
+			limit:    8,
+			expected: "price:\n$(12.50)\neach",
+		},
+		{
+			name:         "break between ideographs",
+			input:        "日本語のテキストです。",
+			limit:        6,
+			displayWidth: true,
+			expected:     "日本語\nのテキ\nストで\nす。",
+		},
+		{
+			name:     "mandatory break",
+			input:    "one\u2028two three",
+			limit:    80,
+			expected: "one\ntwo three",
+		},
+		{
+			name:     "long word overflows",
+			input:    "supercalifragilistic word",
+			limit:    5,
+			expected: "supercalifragilistic\nword",
+		},
+		{
+			name:         "cut long words",
+			input:        "supercalifragilistic word",
+			limit:        5,
+			cutLongWords: true,
+			expected:     "super\ncalif\nragil\nistic\nword",
+		},
+		{
+			name:              "minimum raggedness with mandatory break",
+			input:             "aaa bb cc ddddd\u2028e f g h i j",
+			limit:             6,
+			minimumRaggedness: true,
+			expected:          "aaa\nbb cc\nddddd\ne f g\nh i j",
+		},
+		{
+			name:            "escape sequences begin the next line",
+			input:           "foo   \x1b[1mbar\x1b[0m",
+			limit:           4,
+			escapeSequences: true,
+			expected:        "foo\n\x1b[1mbar\x1b[0m",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			w.UnicodeLineBreaking = true
+			w.StripTrailingNewline = true
+			w.CutLongWords = tt.cutLongWords
+			w.DisplayWidth = tt.displayWidth
+			w.MinimumRaggedness = tt.minimumRaggedness
+			w.EscapeSequences = tt.escapeSequences
+
+			got := w.Wrap(tt.input, tt.limit)
+			if got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_KnuthPlass(t *testing.T) {
+	tests := []struct {
+		name                string
+		input               string
+		limit               int
+		cutLongWords        bool
+		unicodeLineBreaking bool
+		options             func(o *wrap.KnuthPlassOptions)
+		expected            string
+	}{
+		{
+			name:     "evens out lines",
+			input:    "The quick brown fox jumps over the lazy dog and keeps on running.",
+			limit:    16,
+			expected: "The quick brown\nfox jumps over\nthe lazy dog\nand keeps on\nrunning.",
+		},
+		{
+			name:     "avoids breaking after hyphens",
+			input:    "We hold these truths to be self-evident, that all men are created equal.",
+			limit:    18,
+			expected: "We hold these\ntruths to be\nself-evident,\nthat all men are\ncreated equal.",
+		},
+		{
+			name:     "avoids consecutive hyphens",
+			input:    "up-to-date mother-in-law ex-president",
+			limit:    10,
+			expected: "up-to-date\nmother-\nin-law\nex-\npresident",
+		},
+		{
+			name:     "allows consecutive hyphens without flagged demerits",
+			input:    "up-to-date mother-in-law ex-president",
+			limit:    10,
+			options:  func(o *wrap.KnuthPlassOptions) { o.FlaggedDemerits = 0 },
+			expected: "up-to-date\nmother-in-\nlaw ex-\npresident",
+		},
+		{
+			name:     "nothing within tolerance",
+			input:    "The quick brown fox jumps over the lazy dog.",
+			limit:    15,
+			options:  func(o *wrap.KnuthPlassOptions) { o.Tolerance = 1 },
+			expected: "The quick brown\nfox jumps over\nthe lazy dog.",
+		},
+		{
+			name:     "long word overflows",
+			input:    "supercalifragilisticexpialidocious is a word",
+			limit:    10,
+			expected: "supercalifragilisticexpialidocious\nis a word",
+		},
+		{
+			name:         "cut long words",
+			input:        "supercalifragilisticexpialidocious is a word",
+			limit:        10,
+			cutLongWords: true,
+			expected:     "supercalif\nragilistic\nexpialidoc\nious is a\nword",
+		},
+		{
+			name:                "unicode line breaking",
+			input:               "well-known facts about the state-of-the-art",
+			limit:               12,
+			unicodeLineBreaking: true,
+			expected:            "well-known\nfacts about\nthe state-\nof-the-art",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			w.Algorithm = wrap.AlgorithmKnuthPlass
+			w.StripTrailingNewline = true
+			w.CutLongWords = tt.cutLongWords
+			w.UnicodeLineBreaking = tt.unicodeLineBreaking
+			if tt.options != nil {
+				tt.options(&w.KnuthPlass)
+			}
+
+			got := w.Wrap(tt.input, tt.limit)
+			if got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_KnuthPlassLimits(t *testing.T) {
+	w := wrap.NewWrapper()
+	w.Algorithm = wrap.AlgorithmKnuthPlass
+	w.CutLongWords = true
+
+	for _, limit := range testLimits {
+		if limit < 1 {
+			continue
+		}
+		for _, s := range loremIpsums {
+			for _, line := range strings.Split(strings.TrimSuffix(w.Wrap(s, limit), "\n"), "\n") {
+				if n := utf8.RuneCountInString(line); n > limit+1 {
+					t.Errorf("line exceeds limit %d: %q (len=%d)", limit, line, n)
+				}
+			}
+		}
+	}
+}
+
+func TestWrapper_Justify(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		options  func(w *wrap.Wrapper)
+		expected string
+	}{
+		{
+			name:     "greedy",
+			input:    "The quick brown fox jumps over the lazy old dog.",
+			limit:    20,
+			expected: "The  quick brown fox\njumps  over the lazy\nold dog.",
+		},
+		{
+			name:     "justify last line",
+			input:    "The quick brown fox jumps over the lazy old dog.",
+			limit:    20,
+			options:  func(w *wrap.Wrapper) { w.JustifyLastLine = true },
+			expected: "The  quick brown fox\njumps  over the lazy\nold             dog.",
+		},
+		{
+			name:     "each paragraph",
+			input:    "one two three four\nfive six seven eight",
+			limit:    15,
+			expected: "one  two  three\nfour\nfive  six seven\neight",
+		},
+		{
+			name:     "minimum raggedness",
+			input:    "a b c d e f g h i j k l m n o p",
+			limit:    9,
+			options:  func(w *wrap.Wrapper) { w.MinimumRaggedness = true },
+			expected: "a  b  c d\ne  f  g h\ni  j  k l\nm n o p",
+		},
+		{
+			name:     "knuth-plass",
+			input:    "The quick brown fox jumps over the lazy dog and keeps on running.",
+			limit:    16,
+			options:  func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmKnuthPlass },
+			expected: "The  quick brown\nfox  jumps  over\nthe   lazy   dog\nand   keeps   on\nrunning.",
+		},
+		{
+			name:     "display width",
+			input:    "日本 語の テキ スト です",
+			limit:    10,
+			options:  func(w *wrap.Wrapper) { w.DisplayWidth = true },
+			expected: "日本  語の\nテキ  スト\nです",
+		},
+		{
+			name:     "escape sequences",
+			input:    "\x1b[1mbold\x1b[0m and plain text",
+			limit:    12,
+			options:  func(w *wrap.Wrapper) { w.EscapeSequences = true },
+			expected: "\x1b[1mbold\x1b[0m     and\nplain text",
+		},
+		{
+			name:  "boxed",
+			input: "Lorem ipsum dolor sit amet, consectetur adipiscing elit.",
+			limit: 24,
+			options: func(w *wrap.Wrapper) {
+				w.OutputLinePrefix, w.OutputLineSuffix = "| ", " |"
+			},
+			expected: "| Lorem   ipsum  dolor |\n| sit            amet, |\n| consectetur          |\n| adipiscing elit.     |",
+		},
+		{
+			name:  "limit excludes prefix and suffix",
+			input: "one two three four",
+			limit: 10,
+			options: func(w *wrap.Wrapper) {
+				w.OutputLinePrefix, w.OutputLineSuffix = "/* ", " */"
+				w.LimitIncludesPrefixSuffix = false
+			},
+			expected: "/* one    two */\n/* three four */",
+		},
+		{
+			name:  "mandatory break",
+			input: "one two\u2028three four five six",
+			limit: 12,
+			options: func(w *wrap.Wrapper) {
+				w.UnicodeLineBreaking = true
+			},
+			expected: "one two\nthree   four\nfive six",
+		},
+		{
+			name:     "long word",
+			input:    "supercalifragilistic is long",
+			limit:    10,
+			expected: "supercalifragilistic\nis long",
+		},
+		{
+			name:     "no limit",
+			input:    "one two three",
+			limit:    0,
+			options:  func(w *wrap.Wrapper) { w.JustifyLastLine = true },
+			expected: "one two three",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			w.Alignment = wrap.AlignJustify
+			w.StripTrailingNewline = true
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			got := w.Wrap(tt.input, tt.limit)
+			if got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_Align(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		options  func(w *wrap.Wrapper)
+		expected string
+	}{
+		{
+			name:     "center",
+			input:    "The quick brown fox jumps over the lazy dog.",
+			limit:    20,
+			options:  func(w *wrap.Wrapper) { w.Alignment = wrap.AlignCenter },
+			expected: "The quick brown fox\njumps over the lazy\n        dog.",
+		},
+		{
+			name:     "right",
+			input:    "The quick brown fox jumps over the lazy dog.",
+			limit:    20,
+			options:  func(w *wrap.Wrapper) { w.Alignment = wrap.AlignRight },
+			expected: " The quick brown fox\n jumps over the lazy\n                dog.",
+		},
+		{
+			name:  "center with fill",
+			input: "Title",
+			limit: 12,
+			options: func(w *wrap.Wrapper) {
+				w.Alignment, w.Fill = wrap.AlignCenter, '='
+			},
+			expected: "===Title",
+		},
+		{
+			name:  "center box",
+			input: "Usage: wrap [flags] [file ...]",
+			limit: 16,
+			options: func(w *wrap.Wrapper) {
+				w.Alignment, w.Fill = wrap.AlignCenter, '-'
+				w.OutputLinePrefix, w.OutputLineSuffix = "[ ", " ]"
+			},
+			expected: "[ Usage: wrap- ]\n[ --[flags]--- ]\n[ -[file ...]- ]",
+		},
+		{
+			name:  "right box",
+			input: "one two three four",
+			limit: 10,
+			options: func(w *wrap.Wrapper) {
+				w.Alignment = wrap.AlignRight
+				w.OutputLinePrefix, w.OutputLineSuffix = "# ", " #"
+				w.LimitIncludesPrefixSuffix = false
+			},
+			expected: "#    one two #\n# three four #",
+		},
+		{
+			name:  "wide fill",
+			input: "日本",
+			limit: 9,
+			options: func(w *wrap.Wrapper) {
+				w.Alignment, w.Fill, w.DisplayWidth = wrap.AlignRight, '＊', true
+			},
+			expected: "＊＊ 日本",
+		},
+		{
+			name:  "zero width fill",
+			input: "text",
+			limit: 6,
+			options: func(w *wrap.Wrapper) {
+				w.Alignment, w.Fill, w.DisplayWidth = wrap.AlignRight, '\u200b', true
+			},
+			expected: "  text",
+		},
+		{
+			name:  "padding isn't styled",
+			input: "\x1b[7minverse text\x1b[0m",
+			limit: 10,
+			options: func(w *wrap.Wrapper) {
+				w.Alignment, w.ReapplyStyles = wrap.AlignCenter, true
+				w.OutputLineSuffix = "|"
+			},
+			expected: " \x1b[7minverse\x1b[0m |\n  \x1b[7mtext\x1b[0m   |",
+		},
+		{
+			name:     "long word",
+			input:    "supercalifragilistic",
+			limit:    10,
+			options:  func(w *wrap.Wrapper) { w.Alignment = wrap.AlignRight },
+			expected: "supercalifragilistic",
+		},
+		{
+			name:     "no limit",
+			input:    "one two three",
+			limit:    0,
+			options:  func(w *wrap.Wrapper) { w.Alignment = wrap.AlignRight },
+			expected: "one two three",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			w.StripTrailingNewline = true
+			tt.options(&w)
+
+			got := w.Wrap(tt.input, tt.limit)
+			if got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_FirstLineAndContinuationPrefix(t *testing.T) {
+	list := "The quick brown fox jumps over the lazy dog.\nSecond item in the list here"
+
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		options  func(w *wrap.Wrapper)
+		expected string
+	}{
+		{
+			name:     "greedy",
+			input:    list,
+			limit:    16,
+			expected: "- The quick\n  brown fox\n  jumps over the\n  lazy dog.\n- Second item in\n  the list here",
+		},
+		{
+			name:     "minimum raggedness",
+			input:    list,
+			limit:    16,
+			options:  func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmMinimumRaggedness },
+			expected: "- The quick\n  brown fox\n  jumps over\n  the lazy dog.\n- Second item in\n  the list here",
+		},
+		{
+			name:     "knuth-plass",
+			input:    list,
+			limit:    16,
+			options:  func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmKnuthPlass },
+			expected: "- The quick\n  brown fox\n  jumps over the\n  lazy dog.\n- Second item in\n  the list here",
+		},
+		{
+			name:  "first line indent",
+			input: "Lorem ipsum dolor sit amet, consectetur adipiscing elit.",
+			limit: 20,
+			options: func(w *wrap.Wrapper) {
+				w.FirstLinePrefix, w.ContinuationPrefix = "    ", ""
+				w.Algorithm = wrap.AlgorithmMinimumRaggedness
+			},
+			expected: "    Lorem ipsum\ndolor sit amet,\nconsectetur\nadipiscing elit.",
+		},
+		{
+			name:  "with output prefix",
+			input: "wrap [-w width] [-p prefix] [file ...]",
+			limit: 26,
+			options: func(w *wrap.Wrapper) {
+				w.OutputLinePrefix = "// "
+				w.FirstLinePrefix, w.ContinuationPrefix = "Usage: ", "       "
+				w.Breakpoints = " "
+			},
+			expected: "// Usage: wrap [-w width]\n//        [-p prefix]\n//        [file ...]",
+		},
+		{
+			name:  "limit excludes prefix",
+			input: "one two three four",
+			limit: 8,
+			options: func(w *wrap.Wrapper) {
+				w.LimitIncludesPrefixSuffix = false
+			},
+			expected: "- one two\n  three\n  four",
+		},
+		{
+			name:  "cut long words",
+			input: "abcdefghijklmnopqrstuvwxyz abc",
+			limit: 10,
+			options: func(w *wrap.Wrapper) {
+				w.FirstLinePrefix, w.ContinuationPrefix = "* ", ""
+				w.CutLongWords = true
+				w.Algorithm = wrap.AlgorithmKnuthPlass
+			},
+			expected: "* abcdefgh\nijklmnopqr\nstuvwxyz\nabc",
+		},
+		{
+			name:  "right aligned",
+			input: "one two three",
+			limit: 10,
+			options: func(w *wrap.Wrapper) {
+				w.Alignment = wrap.AlignRight
+			},
+			expected: "-  one two\n     three",
+		},
+		{
+			name:     "empty paragraph",
+			input:    "one\n\ntwo",
+			limit:    10,
+			expected: "- one\n- \n- two",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			w.StripTrailingNewline = true
+			w.FirstLinePrefix, w.ContinuationPrefix = "- ", "  "
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			got := w.Wrap(tt.input, tt.limit)
+			if got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_PreserveIndent(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		options  func(w *wrap.Wrapper)
+		expected string
+	}{
+		{
+			name:     "list",
+			input:    "- The quick brown fox jumps over the lazy dog.\n- Second item",
+			limit:    16,
+			expected: "- The quick\n  brown fox\n  jumps over the\n  lazy dog.\n- Second item",
+		},
+		{
+			name:     "nested list",
+			input:    "* one\n  * The quick brown fox jumps over the lazy dog.",
+			limit:    20,
+			expected: "* one\n  * The quick brown\n    fox jumps over\n    the lazy dog.",
+		},
+		{
+			name:     "numbered list",
+			input:    "9. The quick brown fox\n10. jumps over the lazy dog.",
+			limit:    14,
+			expected: "9. The quick\n   brown fox\n10. jumps over\n    the lazy\n    dog.",
+		},
+		{
+			name:     "quote",
+			input:    "> The quick brown fox jumps over the lazy dog.",
+			limit:    20,
+			expected: "> The quick brown\n> fox jumps over the\n> lazy dog.",
+		},
+		{
+			name:     "nested quote",
+			input:    "> > The quick brown fox jumps over the lazy dog.",
+			limit:    20,
+			expected: "> > The quick brown\n> > fox jumps over\n> > the lazy dog.",
+		},
+		{
+			name:     "list in quote",
+			input:    "> - The quick brown fox jumps over the lazy dog.",
+			limit:    20,
+			expected: "> - The quick brown\n>   fox jumps over\n>   the lazy dog.",
+		},
+		{
+			name:     "indent",
+			input:    "\tThe quick brown fox jumps over the lazy dog.",
+			limit:    20,
+			expected: "\tThe quick brown fox\n\tjumps over the lazy\n\tdog.",
+		},
+		{
+			name:     "not a marker",
+			input:    "*not* the quick brown fox",
+			limit:    12,
+			expected: "*not* the\nquick brown\nfox",
+		},
+		{
+			name:     "whitespace only",
+			input:    "  one\n   \n  -",
+			limit:    10,
+			expected: "  one\n\n",
+		},
+		{
+			name:  "with prefixes",
+			input: "- The quick brown fox jumps over the lazy dog.",
+			limit: 20,
+			options: func(w *wrap.Wrapper) {
+				w.OutputLinePrefix = "// "
+				w.FirstLinePrefix, w.ContinuationPrefix = "> ", "> "
+			},
+			expected: "// > - The quick\n// >   brown fox\n// >   jumps over\n// >   the lazy dog.",
+		},
+		{
+			name:  "optimal",
+			input: "- The quick brown fox jumps over the lazy dog.",
+			limit: 16,
+			options: func(w *wrap.Wrapper) {
+				w.Algorithm = wrap.AlgorithmMinimumRaggedness
+			},
+			expected: "- The quick\n  brown fox\n  jumps over\n  the lazy dog.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			w.StripTrailingNewline = true
+			w.PreserveIndent = true
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			got := w.Wrap(tt.input, tt.limit)
+			if got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_Unwrap(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  func(w *wrap.Wrapper)
+		expected string
+	}{
+		{
+			name:     "paragraphs",
+			input:    "The quick brown\nfox jumps over\nthe lazy dog.\n\n\nSecond\nparagraph\n",
+			expected: "The quick brown fox jumps over the lazy dog.\n\n\nSecond paragraph\n",
+		},
+		{
+			name:     "hyphens",
+			input:    "a well-\nknown state-of-\nthe-art plan -\nor not",
+			expected: "a well-known state-of-the-art plan - or not",
+		},
+		{
+			name:     "suspended hyphens",
+			input:    "pre-\nand post-\nwar, full-\nor part-\ntime, up-\nto-date",
+			expected: "pre- and post-war, full- or part-time, up-to-date",
+		},
+		{
+			name:     "other hyphens at line ends",
+			input:    "pre-\nlonglong, pre-\nand",
+			expected: "pre-longlong, pre- and",
+		},
+		{
+			name:     "spaces",
+			input:    "One.  Two   three  \n  four",
+			expected: "One. Two three\n  four",
+		},
+		{
+			name:     "indent change",
+			input:    "Some code:\n    if x {\n    }\nMore text\nhere",
+			expected: "Some code:\n    if x { }\nMore text here",
+		},
+		{
+			name:     "wide characters",
+			input:    "日本語の\nテキスト。\nEnglish\nwords",
+			expected: "日本語のテキスト。 English words",
+		},
+		{
+			name:  "comment",
+			input: "// The quick brown\n// fox jumps.\n//\n// Second",
+			options: func(w *wrap.Wrapper) {
+				w.TrimInputPrefix = "// "
+			},
+			expected: "The quick brown fox jumps.\n\nSecond",
+		},
+		{
+			name:  "box",
+			input: "| The  quick  brown |\n| fox.             |",
+			options: func(w *wrap.Wrapper) {
+				w.TrimInputPrefix, w.TrimInputSuffix = "| ", " |"
+			},
+			expected: "The quick brown fox.",
+		},
+		{
+			name:  "first line and continuation prefix",
+			input: "Usage: wrap [-w width]\n       [file ...]\nUsage:\nUsage: wrap -h",
+			options: func(w *wrap.Wrapper) {
+				w.FirstLinePrefix, w.ContinuationPrefix = "Usage: ", "       "
+			},
+			expected: "wrap [-w width] [file ...]\n\nwrap -h",
+		},
+		{
+			name:  "preserve indent",
+			input: "- The quick\n  brown fox\n- Second\n  1. Nested\n     item\n  > quote\n  continued",
+			options: func(w *wrap.Wrapper) {
+				w.PreserveIndent = true
+			},
+			expected: "- The quick brown fox\n- Second\n  1. Nested item\n  > quote\n  continued",
+		},
+		{
+			name:  "quotes",
+			input: "> The quick\n> brown fox\n> > Nested\n> > quote\n> - List\n>   item",
+			options: func(w *wrap.Wrapper) {
+				w.PreserveIndent = true
+			},
+			expected: "> The quick brown fox\n> > Nested quote\n> - List item",
+		},
+		{
+			name:     "custom newline",
+			input:    "one\r\ntwo\r\n\r\nthree\r\n",
+			options:  func(w *wrap.Wrapper) { w.Newline = "\r\n" },
+			expected: "one two\r\n\r\nthree\r\n",
+		},
+		{
+			name:     "empty",
+			input:    "",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			got := w.Unwrap(tt.input)
+			if got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_Reflow(t *testing.T) {
+	tests := []struct {
+		name    string
+		inputs  []string
+		options func(w *wrap.Wrapper)
+	}{
+		{name: "default", inputs: loremIpsums},
+		{name: "minimum raggedness", inputs: loremIpsums, options: func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmMinimumRaggedness }},
+		{name: "knuth-plass", inputs: loremIpsums, options: func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmKnuthPlass }},
+		{name: "comment", inputs: loremIpsums, options: func(w *wrap.Wrapper) { w.OutputLinePrefix, w.TrimInputPrefix = "// ", "// " }},
+		{
+			name:   "justified box",
+			inputs: loremIpsums,
+			options: func(w *wrap.Wrapper) {
+				w.Alignment = wrap.AlignJustify
+				w.OutputLinePrefix, w.OutputLineSuffix = "| ", " |"
+				w.TrimInputPrefix, w.TrimInputSuffix = "| ", " |"
+			},
+		},
+		{
+			name:    "hanging indent",
+			inputs:  loremIpsums,
+			options: func(w *wrap.Wrapper) { w.FirstLinePrefix, w.ContinuationPrefix = "Usage: ", "       " },
+		},
+		{
+			name: "preserve indent",
+			inputs: []string{
+				"- one two three-four five six seven eight nine ten\n  1. nested item with well-known state-of-the-art words\n\n> quoted text that goes on for a while longer than the limit",
+				"> > nested quote that goes on for a while\n> - list in a quote that goes on for a while",
+				"    Indented paragraph that is long enough to wrap.\nFollowed by a paragraph that isn't indented.",
+				"Items are listed as either - a dash or 2. a number followed by text",
+				"- Items are listed as either - a dash, > a quote or 2. a number followed by text",
+			},
+			options: func(w *wrap.Wrapper) { w.PreserveIndent = true },
+		},
+		{
+			name:   "suspended hyphens",
+			inputs: []string{"Both pre- and post-war housing, full- or part-time work and two- to three-year terms"},
+		},
+		{
+			name:    "unicode line breaking",
+			inputs:  []string{"日本語のテキストです。これは長い文章です。 English words mixed in, self-evident"},
+			options: func(w *wrap.Wrapper) { w.UnicodeLineBreaking, w.DisplayWidth = true, true },
+		},
+	}
+
+	limits := []int{5, 10, 17, 25, 40, 80}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			for _, s := range tt.inputs {
+				for _, a := range limits {
+					for _, b := range limits {
+						want := w.Reflow(s, b)
+						if got := w.Reflow(w.Reflow(s, a), b); got != want {
+							t.Errorf("reflowing at %d then %d:\ngot  %q\nwant %q", a, b, got, want)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWrapper_WrapMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		options  func(w *wrap.Wrapper)
+		expected string
+	}{
+		{
+			name:     "paragraphs",
+			input:    "The quick brown fox\njumps over the lazy dog.\n\nSecond paragraph.",
+			limit:    20,
+			expected: "The quick brown fox\njumps over the lazy\ndog.\n\nSecond paragraph.\n",
+		},
+		{
+			name:     "fenced code",
+			input:    "```go\nfunc main() { fmt.Println(\"hello world\") }\n```\n~~~\nlong line of code that stays put\n~~~",
+			limit:    10,
+			expected: "```go\nfunc main() { fmt.Println(\"hello world\") }\n```\n~~~\nlong line of code that stays put\n~~~\n",
+		},
+		{
+			name:     "indented code",
+			input:    "Some text\n\n    long line of code that stays put\n\n    more code\nAfter the code block",
+			limit:    10,
+			expected: "Some text\n\n    long line of code that stays put\n\n    more code\nAfter the\ncode block\n",
+		},
+		{
+			name:     "headings",
+			input:    "# A long ATX heading that stays put\nSetext heading that stays put\n---",
+			limit:    10,
+			expected: "# A long ATX heading that stays put\nSetext heading that stays put\n---\n",
+		},
+		{
+			name:     "table",
+			input:    "| Name | Description |\n|------|-------------|\n| wrap | Wraps some long text |",
+			limit:    10,
+			expected: "| Name | Description |\n|------|-------------|\n| wrap | Wraps some long text |\n",
+		},
+		{
+			name:     "html and link reference definitions",
+			input:    "<div class=\"note\">\nSome HTML content that stays put\n</div>\n\n[ref]: https://example.com/a/long/path \"Title\"",
+			limit:    10,
+			expected: "<div class=\"note\">\nSome HTML content that stays put\n</div>\n\n[ref]: https://example.com/a/long/path \"Title\"\n",
+		},
+		{
+			name:     "lists",
+			input:    "- The quick brown fox jumps\nover the lazy dog.\n- Second\n\n  1. A nested ordered item\n  10) Wider markers",
+			limit:    16,
+			expected: "- The quick\n  brown fox\n  jumps over the\n  lazy dog.\n- Second\n\n  1. A nested\n     ordered\n     item\n  10) Wider\n      markers\n",
+		},
+		{
+			name:     "blockquotes",
+			input:    "> The quick brown fox\n> jumps over\nthe lazy dog.\n>\n> > Nested quote text",
+			limit:    16,
+			expected: "> The quick\n> brown fox\n> jumps over the\n> lazy dog.\n>\n> > Nested quote\n> > text\n",
+		},
+		{
+			name:     "inline spans",
+			input:    "Run `go test ./...` or see [the docs](https://example.com/docs \"Go docs\") and <https://example.com>.",
+			limit:    10,
+			expected: "Run\n`go test ./...`\nor see\n[the\ndocs](https://example.com/docs \"Go docs\")\nand\n<https://example.com>.\n",
+		},
+		{
+			name:     "no block syntax at line start",
+			input:    "Counting 1. then - and # and > with 2) done",
+			limit:    10,
+			expected: "Counting 1.\nthen -\nand #\nand >\nwith 2)\ndone\n",
+		},
+		{
+			name:     "inline html at a break",
+			input:    "aaaa <x\nsome text with <span>inline</span> html",
+			limit:    16,
+			expected: "aaaa <x some\ntext\nwith <span>inline</span>\nhtml\n",
+		},
+		{
+			name:     "inline html past the limit",
+			input:    "aaaa <x",
+			limit:    6,
+			expected: "aaaa <x\n",
+		},
+		{
+			name:     "hard line breaks",
+			input:    "First line  \nSecond line\\\nthird line",
+			limit:    6,
+			expected: "First\nline  \nSecond\nline\\\nthird\nline\n",
+		},
+		{
+			name:  "options",
+			input: "- The quick brown fox",
+			limit: 15,
+			options: func(w *wrap.Wrapper) {
+				w.OutputLinePrefix = "// "
+				w.LimitIncludesPrefixSuffix = true
+				w.StripTrailingNewline = true
+			},
+			expected: "// - The quick\n//   brown fox",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			got := w.WrapMarkdown(tt.input, tt.limit)
+			if got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_Lines(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		options  func(w *wrap.Wrapper)
+		expected []wrap.Line
+	}{
+		{
+			name:  "breakpoints",
+			input: "The well-known fox",
+			limit: 10,
+			expected: []wrap.Line{
+				{Text: "The well-", Start: 0, End: 9, Width: 9, Break: wrap.BreakBreakpoint},
+				{Text: "known fox", Start: 9, End: 18, Width: 9, Break: wrap.BreakEnd},
+			},
+		},
+		{
+			name:  "overflow",
+			input: "a verylongword b",
+			limit: 5,
+			expected: []wrap.Line{
+				{Text: "a", Start: 0, End: 1, Width: 1, Break: wrap.BreakBreakpoint},
+				{Text: "verylongword", Start: 2, End: 14, Width: 12, Break: wrap.BreakOverflow},
+				{Text: "b", Start: 15, End: 16, Width: 1, Break: wrap.BreakEnd},
+			},
+		},
+		{
+			name:  "cut long words",
+			input: "abcdefgh ij",
+			limit: 3,
+			options: func(w *wrap.Wrapper) {
+				w.CutLongWords = true
+			},
+			expected: []wrap.Line{
+				{Text: "abc", Start: 0, End: 3, Width: 3, Break: wrap.BreakCut},
+				{Text: "def", Start: 3, End: 6, Width: 3, Break: wrap.BreakCut},
+				{Text: "gh", Start: 6, End: 8, Width: 2, Break: wrap.BreakBreakpoint},
+				{Text: "ij", Start: 9, End: 11, Width: 2, Break: wrap.BreakEnd},
+			},
+		},
+		{
+			name:  "hyphenation",
+			input: "a computer algorithm",
+			limit: 8,
+			options: func(w *wrap.Wrapper) {
+				w.Hyphenation.Hyphenator = wrap.HyphenatorEnUS()
+			},
+			expected: []wrap.Line{
+				{Text: "a com-", Start: 0, End: 5, Width: 6, Break: wrap.BreakHyphen},
+				{Text: "puter", Start: 5, End: 10, Width: 5, Break: wrap.BreakBreakpoint},
+				{Text: "algo-", Start: 11, End: 15, Width: 5, Break: wrap.BreakHyphen},
+				{Text: "rithm", Start: 15, End: 20, Width: 5, Break: wrap.BreakEnd},
+			},
+		},
+		{
+			name:  "soft hyphen",
+			input: "inter\u00adna\u00adtion\u00adal",
+			limit: 8,
+			expected: []wrap.Line{
+				{Text: "inter\u00adna-", Start: 0, End: 9, Width: 8, Break: wrap.BreakHyphen},
+				{Text: "tion\u00adal", Start: 11, End: 19, Width: 6, Break: wrap.BreakEnd},
+			},
+		},
+		{
+			name:  "hyphenation with minimum raggedness",
+			input: "a computer algorithm",
+			limit: 8,
+			options: func(w *wrap.Wrapper) {
+				w.Hyphenation.Hyphenator = wrap.HyphenatorEnUS()
+				w.Algorithm = wrap.AlgorithmMinimumRaggedness
+			},
+			expected: []wrap.Line{
+				{Text: "a com-", Start: 0, End: 5, Width: 6, Break: wrap.BreakHyphen},
+				{Text: "puter", Start: 5, End: 10, Width: 5, Break: wrap.BreakBreakpoint},
+				{Text: "algo-", Start: 11, End: 15, Width: 5, Break: wrap.BreakHyphen},
+				{Text: "rithm", Start: 15, End: 20, Width: 5, Break: wrap.BreakEnd},
+			},
+		},
+		{
+			name:  "newlines and prefixes",
+			input: "// one two three\n// four",
+			limit: 10,
+			options: func(w *wrap.Wrapper) {
+				w.TrimInputPrefix = "// "
+				w.OutputLinePrefix = "# "
+				w.ContinuationPrefix = "  "
+			},
+			expected: []wrap.Line{
+				{Text: "one two", Start: 3, End: 10, Width: 7, Break: wrap.BreakBreakpoint, Prefix: "# "},
+				{Text: "three", Start: 11, End: 16, Width: 5, Break: wrap.BreakNewline, Prefix: "#   "},
+				{Text: "four", Start: 20, End: 24, Width: 4, Break: wrap.BreakEnd, Prefix: "# "},
+			},
+		},
+		{
+			name:  "preserve indent",
+			input: "  - one two three",
+			limit: 10,
+			options: func(w *wrap.Wrapper) {
+				w.PreserveIndent = true
+			},
+			expected: []wrap.Line{
+				{Text: "one", Start: 4, End: 7, Width: 3, Break: wrap.BreakBreakpoint, Prefix: "  - "},
+				{Text: "two", Start: 8, End: 11, Width: 3, Break: wrap.BreakBreakpoint, Prefix: "    "},
+				{Text: "three", Start: 12, End: 17, Width: 5, Break: wrap.BreakEnd, Prefix: "    "},
+			},
+		},
+		{
+			name:  "justify",
+			input: "a b c d e f",
+			limit: 8,
+			options: func(w *wrap.Wrapper) {
+				w.Alignment = wrap.AlignJustify
+				w.OutputLineSuffix = " |"
+			},
+			expected: []wrap.Line{
+				{Text: "a  b c", Start: 0, End: 5, Width: 6, Break: wrap.BreakBreakpoint, Suffix: " |"},
+				{Text: "d e f", Start: 6, End: 11, Width: 5, Break: wrap.BreakEnd, Suffix: "  |"},
+			},
+		},
+		{
+			name:  "reapply styles",
+			input: "\x1b[31mred text\x1b[0m",
+			limit: 4,
+			options: func(w *wrap.Wrapper) {
+				w.ReapplyStyles = true
+			},
+			expected: []wrap.Line{
+				{Text: "\x1b[31mred", Start: 0, End: 8, Width: 3, Break: wrap.BreakBreakpoint, Suffix: "\x1b[0m"},
+				{Text: "text\x1b[0m", Start: 9, End: 17, Width: 4, Break: wrap.BreakEnd, Prefix: "\x1b[31m"},
+			},
+		},
+		{
+			name:  "mandatory break",
+			input: "one two three",
+			limit: 20,
+			options: func(w *wrap.Wrapper) {
+				w.UnicodeLineBreaking = true
+			},
+			expected: []wrap.Line{
+				{Text: "one two", Start: 0, End: 7, Width: 7, Break: wrap.BreakNewline},
+				{Text: "three", Start: 10, End: 15, Width: 5, Break: wrap.BreakEnd},
+			},
+		},
+		{
+			name:  "minimum raggedness",
+			input: "aaa bbbbbbb c",
+			limit: 4,
+			options: func(w *wrap.Wrapper) {
+				w.Algorithm = wrap.AlgorithmMinimumRaggedness
+				w.CutLongWords = true
+			},
+			expected: []wrap.Line{
+				{Text: "aaa", Start: 0, End: 3, Width: 3, Break: wrap.BreakBreakpoint},
+				{Text: "bbbb", Start: 4, End: 8, Width: 4, Break: wrap.BreakCut},
+				{Text: "bbb", Start: 8, End: 11, Width: 3, Break: wrap.BreakBreakpoint},
+				{Text: "c", Start: 12, End: 13, Width: 1, Break: wrap.BreakEnd},
+			},
+		},
+		{
+			name:  "knuth-plass",
+			input: "aaa bbbbbbb c",
+			limit: 4,
+			options: func(w *wrap.Wrapper) {
+				w.Algorithm = wrap.AlgorithmKnuthPlass
+			},
+			expected: []wrap.Line{
+				{Text: "aaa", Start: 0, End: 3, Width: 3, Break: wrap.BreakBreakpoint},
+				{Text: "bbbbbbb", Start: 4, End: 11, Width: 7, Break: wrap.BreakOverflow},
+				{Text: "c", Start: 12, End: 13, Width: 1, Break: wrap.BreakEnd},
+			},
+		},
+		{
+			name:  "tabs",
+			input: "a\tb c\td",
+			limit: 6,
+			options: func(w *wrap.Wrapper) {
+				w.Tabs.Width = 4
+			},
+			expected: []wrap.Line{
+				{Text: "a   b", Start: 0, End: 3, Width: 5, Break: wrap.BreakBreakpoint},
+				{Text: "c   d", Start: 4, End: 7, Width: 5, Break: wrap.BreakEnd},
+			},
+		},
+		{
+			name:  "kept tabs",
+			input: "a\tb c\td",
+			limit: 10,
+			options: func(w *wrap.Wrapper) {
+				w.Tabs = wrap.TabOptions{Width: 4, Keep: true}
+				w.OutputLinePrefix = "\t"
+			},
+			expected: []wrap.Line{
+				{Text: "a\tb", Start: 0, End: 3, Width: 5, Break: wrap.BreakBreakpoint, Prefix: "\t"},
+				{Text: "c\td", Start: 4, End: 7, Width: 5, Break: wrap.BreakEnd, Prefix: "\t"},
+			},
+		},
+		{
+			name:  "measurer",
+			input: "ill will mmm",
+			limit: 10,
+			options: func(w *wrap.Wrapper) {
+				w.Measurer = proportional
+			},
+			expected: []wrap.Line{
+				{Text: "ill", Start: 0, End: 3, Width: 3, Break: wrap.BreakBreakpoint},
+				{Text: "will", Start: 4, End: 8, Width: 6, Break: wrap.BreakBreakpoint},
+				{Text: "mmm", Start: 9, End: 12, Width: 9, Break: wrap.BreakEnd},
+			},
+		},
+		{
+			name:  "empty",
+			input: "",
+			limit: 10,
+			expected: []wrap.Line{
+				{Break: wrap.BreakEnd},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			got := w.Lines(tt.input, tt.limit)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("got %d lines, want %d: %+v", len(got), len(tt.expected), got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("line %d: got %+v, want %+v", i, got[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWrapper_MaxLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		limit     int
+		options   func(w *wrap.Wrapper)
+		expected  string
+		truncated bool
+	}{
+		{
+			name:      "truncated",
+			input:     "The quick brown fox jumps over the lazy dog",
+			limit:     10,
+			options:   func(w *wrap.Wrapper) { w.MaxLines = 2 },
+			expected:  "The quick\nbrown fox…\n",
+			truncated: true,
+		},
+		{
+			name:     "fits",
+			input:    "The quick brown fox",
+			limit:    10,
+			options:  func(w *wrap.Wrapper) { w.MaxLines = 2 },
+			expected: "The quick\nbrown fox\n",
+		},
+		{
+			name:      "more input lines",
+			input:     "The quick\nbrown fox",
+			limit:     10,
+			options:   func(w *wrap.Wrapper) { w.MaxLines = 1 },
+			expected:  "The quick…\n",
+			truncated: true,
+		},
+		{
+			name:  "ellipsis wider than limit",
+			input: "The quick brown fox",
+			limit: 2,
+			options: func(w *wrap.Wrapper) {
+				w.MaxLines, w.Ellipsis = 1, "..."
+			},
+			expected:  "..\n",
+			truncated: true,
+		},
+		{
+			name:      "backs off to a breakpoint",
+			input:     "one two three four",
+			limit:     12,
+			options:   func(w *wrap.Wrapper) { w.MaxLines, w.Ellipsis = 1, " [more]" },
+			expected:  "one [more]\n",
+			truncated: true,
+		},
+		{
+			name:      "cuts a long word",
+			input:     "Thequickbrownfox jumps",
+			limit:     10,
+			options:   func(w *wrap.Wrapper) { w.MaxLines = 1 },
+			expected:  "Thequickb…\n",
+			truncated: true,
+		},
+		{
+			name:  "display width",
+			input: "日本語のテキストです",
+			limit: 8,
+			options: func(w *wrap.Wrapper) {
+				w.MaxLines, w.DisplayWidth, w.CutLongWords = 1, true, true
+			},
+			expected:  "日本語…\n",
+			truncated: true,
+		},
+		{
+			name:  "prefix",
+			input: "The quick brown fox jumps over",
+			limit: 13,
+			options: func(w *wrap.Wrapper) {
+				w.MaxLines, w.OutputLinePrefix, w.StripTrailingNewline = 2, "// ", true
+			},
+			expected:  "// The quick\n// brown fox…",
+			truncated: true,
+		},
+		{
+			name:      "unwrapped",
+			input:     "The quick brown fox\njumps over",
+			limit:     0,
+			options:   func(w *wrap.Wrapper) { w.MaxLines = 1 },
+			expected:  "The quick brown fox…\n",
+			truncated: true,
+		},
+		{
+			name:  "minimum raggedness",
+			input: "aaa bb cc dd eeee ffff",
+			limit: 7,
+			options: func(w *wrap.Wrapper) {
+				w.MaxLines, w.Algorithm = 2, wrap.AlgorithmMinimumRaggedness
+			},
+			expected:  "aaa\nbb cc…\n",
+			truncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			got, truncated := w.WrapTruncated(tt.input, tt.limit)
+			if got != tt.expected || truncated != tt.truncated {
+				t.Errorf("got %q, %v, want %q, %v", got, truncated, tt.expected, tt.truncated)
+			}
+			if wrapped := w.Wrap(tt.input, tt.limit); wrapped != got {
+				t.Errorf("Wrap gave %q, want %q", wrapped, got)
+			}
+		})
+	}
+}
+
+func TestWrapper_Truncate(t *testing.T) {
+	const fox = "The quick brown fox jumps"
+	const path = "/home/user/projects/wrap/main.go"
+
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		options  func(w *wrap.Wrapper)
+		expected string
+	}{
+		{
+			name:     "fits",
+			input:    fox,
+			limit:    25,
+			expected: fox,
+		},
+		{
+			name:     "unlimited",
+			input:    fox,
+			limit:    0,
+			expected: fox,
+		},
+		{
+			name:     "end",
+			input:    fox,
+			limit:    12,
+			expected: "The quick b…",
+		},
+		{
+			name:     "start",
+			input:    fox,
+			limit:    12,
+			options:  func(w *wrap.Wrapper) { w.TruncateMode = wrap.TruncateStart },
+			expected: "…n fox jumps",
+		},
+		{
+			name:     "middle",
+			input:    fox,
+			limit:    12,
+			options:  func(w *wrap.Wrapper) { w.TruncateMode = wrap.TruncateMiddle },
+			expected: "The qu…jumps",
+		},
+		{
+			name:     "end at breakpoints",
+			input:    fox,
+			limit:    12,
+			options:  func(w *wrap.Wrapper) { w.TruncateAtBreakpoints = true },
+			expected: "The quick…",
+		},
+		{
+			name:  "start at breakpoints",
+			input: fox,
+			limit: 12,
+			options: func(w *wrap.Wrapper) {
+				w.TruncateMode, w.TruncateAtBreakpoints = wrap.TruncateStart, true
+			},
+			expected: "…fox jumps",
+		},
+		{
+			name:  "middle at breakpoints",
+			input: fox,
+			limit: 12,
+			options: func(w *wrap.Wrapper) {
+				w.TruncateMode, w.TruncateAtBreakpoints = wrap.TruncateMiddle, true
+			},
+			expected: "The…jumps",
+		},
+		{
+			name:  "path",
+			input: path,
+			limit: 20,
+			options: func(w *wrap.Wrapper) {
+				w.TruncateMode, w.TruncateAtBreakpoints, w.Breakpoints = wrap.TruncateMiddle, true, "/"
+			},
+			expected: "/home/user…/main.go",
+		},
+		{
+			name:  "path start",
+			input: path,
+			limit: 20,
+			options: func(w *wrap.Wrapper) {
+				w.TruncateMode, w.TruncateAtBreakpoints, w.Breakpoints = wrap.TruncateStart, true, "/"
+			},
+			expected: "…/wrap/main.go",
+		},
+		{
+			name:  "no breakpoint to cut at",
+			input: "abcdefghijklmnop",
+			limit: 8,
+			options: func(w *wrap.Wrapper) {
+				w.TruncateAtBreakpoints, w.Ellipsis = true, "..."
+			},
+			expected: "abcde...",
+		},
+		{
+			name:     "display width",
+			input:    "日本語のテキストです",
+			limit:    9,
+			options:  func(w *wrap.Wrapper) { w.DisplayWidth = true },
+			expected: "日本語の…",
+		},
+		{
+			name:     "escape sequences",
+			input:    "\x1b[31mred text\x1b[0m here",
+			limit:    6,
+			options:  func(w *wrap.Wrapper) { w.EscapeSequences = true },
+			expected: "\x1b[31mred t…\x1b[0m",
+		},
+		{
+			name:     "ellipsis too wide",
+			input:    "abcdefghij",
+			limit:    2,
+			options:  func(w *wrap.Wrapper) { w.Ellipsis = "..." },
+			expected: "..",
+		},
+		{
+			name:     "protected span cut",
+			input:    "https://example.com/a-long-path",
+			limit:    10,
+			options:  func(w *wrap.Wrapper) { w.Protect.URLs = true },
+			expected: "https://e…",
+		},
+		{
+			name:     "protected span at breakpoints",
+			input:    "see the https://example.com/a-long-path now",
+			limit:    30,
+			options:  func(w *wrap.Wrapper) { w.Protect.URLs = true; w.TruncateAtBreakpoints = true },
+			expected: "see the…",
+		},
+		{
+			name:  "protected span at breakpoints from the start",
+			input: "see the https://example.com/a-long-path now",
+			limit: 30,
+			options: func(w *wrap.Wrapper) {
+				w.Protect.URLs = true
+				w.TruncateAtBreakpoints = true
+				w.TruncateMode = wrap.TruncateStart
+			},
+			expected: "…now",
+		},
+		{
+			name:     "tabs",
+			input:    "a\tb c\td",
+			limit:    6,
+			options:  func(w *wrap.Wrapper) { w.Tabs.Width = 4 },
+			expected: "a   b…",
+		},
+		{
+			name:     "tabs that fit",
+			input:    "a\tb",
+			limit:    6,
+			options:  func(w *wrap.Wrapper) { w.Tabs.Width = 4 },
+			expected: "a   b",
+		},
+		{
+			name:     "kept tabs",
+			input:    "a\tb c\td",
+			limit:    6,
+			options:  func(w *wrap.Wrapper) { w.Tabs = wrap.TabOptions{Width: 4, Keep: true} },
+			expected: "a\t…",
+		},
+		{
+			name:     "measurer",
+			input:    "ill will mmm",
+			limit:    8,
+			options:  func(w *wrap.Wrapper) { w.Measurer = proportional },
+			expected: "ill …",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			got := w.Truncate(tt.input, tt.limit)
+			if got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTruncate_Shorthand(t *testing.T) {
+	if got, want := wrap.Truncate("The quick brown fox", 10), "The quick…"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWrapper_Measure(t *testing.T) {
+	const fox = "The quick brown fox jumps over the lazy dog."
+
+	tests := []struct {
+		name    string
+		input   string
+		limit   int
+		options func(w *wrap.Wrapper)
+		lines   int
+		width   int
+	}{
+		{name: "unwrapped", input: fox, limit: 0, lines: 1, width: 44},
+		{name: "wrapped", input: fox, limit: 20, lines: 3, width: 19},
+		{name: "narrow", input: fox, limit: 10, lines: 5, width: 10},
+		{name: "empty", input: "", limit: 10, lines: 1, width: 0},
+		{name: "newlines", input: "one\n\ntwo three", limit: 5, lines: 4, width: 5},
+		{
+			name:    "prefix",
+			input:   fox,
+			limit:   20,
+			options: func(w *wrap.Wrapper) { w.OutputLinePrefix = "// " },
+			lines:   3,
+			width:   18,
+		},
+		{
+			name:  "alignment",
+			input: fox,
+			limit: 20,
+			options: func(w *wrap.Wrapper) {
+				w.Alignment, w.OutputLineSuffix = wrap.AlignCenter, "|"
+			},
+			lines: 3,
+			width: 20,
+		},
+		{
+			name:    "display width",
+			input:   "日本語 テキスト",
+			limit:   10,
+			options: func(w *wrap.Wrapper) { w.DisplayWidth = true },
+			lines:   2,
+			width:   8,
+		},
+		{
+			name:    "max lines",
+			input:   fox,
+			limit:   10,
+			options: func(w *wrap.Wrapper) { w.MaxLines = 2 },
+			lines:   2,
+			width:   10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			lines, width := w.Measure(tt.input, tt.limit)
+			if lines != tt.lines || width != tt.width {
+				t.Errorf("got %d lines %d wide, want %d lines %d wide", lines, width, tt.lines, tt.width)
+			}
+			if got := w.LineCount(tt.input, tt.limit); got != lines {
+				t.Errorf("LineCount gave %d, want %d", got, lines)
+			}
+			if got := w.MaxWidth(tt.input, tt.limit); got != width {
+				t.Errorf("MaxWidth gave %d, want %d", got, width)
+			}
+
+			// The measurements must match the wrapped text
+			wrapped := strings.Split(strings.TrimSuffix(w.Wrap(tt.input, tt.limit), "\n"), "\n")
+			if len(wrapped) != lines {
+				t.Errorf("Wrap gave %d lines, want %d", len(wrapped), lines)
+			}
+		})
+	}
+}
+
+func TestWrapper_MeasureAllocs(t *testing.T) {
+	w := wrap.NewWrapper()
+	w.OutputLinePrefix = "// "
+	input := loremIpsums[0] + "\n\n" + loremIpsums[1]
+
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{"Measure", func() { w.Measure(input, 40) }},
+		{"LineCount", func() { w.LineCount(input, 40) }},
+		{"MaxWidth", func() { w.MaxWidth(input, 40) }},
+		{"WidthForLines", func() { w.WidthForLines(input, 10) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if n := testing.AllocsPerRun(10, tt.fn); n != 0 {
+				t.Errorf("got %v allocations, want 0", n)
+			}
+		})
+	}
+}
+
+func TestWrapper_WrapAllocs(t *testing.T) {
+	w := wrap.NewWrapper()
+	input := loremIpsums[0] + "\n\n" + loremIpsums[1]
+
+	for _, limit := range []int{0, 1, 10, 80, 500} {
+		t.Run(fmt.Sprint(limit), func(t *testing.T) {
+			// The only allocation is the wrapped string
+			if n := testing.AllocsPerRun(10, func() { w.Wrap(input, limit) }); n != 1 {
+				t.Errorf("got %v allocations, want 1", n)
+			}
+		})
+	}
+}
+
+func TestWrapper_WidthForLines(t *testing.T) {
+	const fox = "The quick brown fox jumps over the lazy dog."
+
+	tests := []struct {
+		name     string
+		input    string
+		lines    int
+		options  func(w *wrap.Wrapper)
+		expected int
+	}{
+		{name: "one line", input: fox, lines: 1, expected: 44},
+		{name: "two lines", input: fox, lines: 2, expected: 24},
+		{name: "three lines", input: fox, lines: 3, expected: 15},
+		{name: "word per line", input: fox, lines: 9, expected: 1},
+		{name: "too many newlines", input: "one\ntwo", lines: 1, expected: -1},
+		{name: "newlines", input: "one\ntwo", lines: 2, expected: 1},
+		{
+			name:     "prefix",
+			input:    fox,
+			lines:    3,
+			options:  func(w *wrap.Wrapper) { w.OutputLinePrefix = "// " },
+			expected: 18,
+		},
+		{
+			name:     "minimum raggedness",
+			input:    fox,
+			lines:    3,
+			options:  func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmMinimumRaggedness },
+			expected: 15,
+		},
+		{
+			name:     "max lines ignored",
+			input:    fox,
+			lines:    3,
+			options:  func(w *wrap.Wrapper) { w.MaxLines = 1 },
+			expected: 15,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			got := w.WidthForLines(tt.input, tt.lines)
+			if got != tt.expected {
+				t.Errorf("got %d, want %d", got, tt.expected)
+			}
+			if got > 1 && w.MaxLines == 0 {
+				if n := w.LineCount(tt.input, got); n > tt.lines {
+					t.Errorf("wraps into %d lines at %d", n, got)
+				}
+				if n := w.LineCount(tt.input, got-1); n <= tt.lines {
+					t.Errorf("also wraps into %d lines at %d", n, got-1)
+				}
+			}
+		})
+	}
+}
+
+func TestWrapper_Balanced(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		options  func(w *wrap.Wrapper)
+		expected string
+	}{
+		{
+			name:     "heading",
+			input:    "Getting started with the wrap package",
+			limit:    30,
+			expected: "Getting started with\nthe wrap package\n",
+		},
+		{
+			name:     "fewest lines",
+			input:    "aa bbb cc d eee ffff ggggggg",
+			limit:    14,
+			expected: "aa bbb cc\nd eee ffff\nggggggg\n",
+		},
+		{
+			name:     "last line",
+			input:    "aa bb ccc dddd eeeeeee fffff",
+			limit:    10,
+			expected: "aa bb\nccc dddd\neeeeeee\nfffff\n",
+		},
+		{
+			name:     "hyphens",
+			input:    "A well-known heading with hyphenated-words in it",
+			limit:    36,
+			expected: "A well-known heading with\nhyphenated-words in it\n",
+		},
+		{
+			name:     "single line",
+			input:    "Short label",
+			limit:    30,
+			expected: "Short label\n",
+		},
+		{
+			name:     "space before a hyphen",
+			input:    "aaaa --bb",
+			limit:    8,
+			expected: "aaaa\n--bb\n",
+		},
+		{
+			name:     "overflowing word",
+			input:    "aaaaaaaaa bb ccc",
+			limit:    5,
+			expected: "aaaaaaaaa\nbb\nccc\n",
+		},
+		{
+			name:     "first line prefix",
+			input:    "The quick brown fox jumps over the lazy dog",
+			limit:    30,
+			options:  func(w *wrap.Wrapper) { w.FirstLinePrefix = "Note: " },
+			expected: "Note: The quick brown fox\njumps over the lazy dog\n",
+		},
+		{
+			name:     "cut long words",
+			input:    "abcdefghijklmnopqrstuvwxyz",
+			limit:    10,
+			options:  func(w *wrap.Wrapper) { w.CutLongWords = true },
+			expected: "abcdefghij\nklmnopqrst\nuvwxyz\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			w.Algorithm = wrap.AlgorithmBalanced
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			got := w.Wrap(tt.input, tt.limit)
+			if got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+
+			// Balancing never uses more lines than the greedy algorithm
+			w.Algorithm = wrap.AlgorithmGreedy
+			if n, greedy := strings.Count(got, "\n"), w.LineCount(tt.input, tt.limit); n != greedy {
+				t.Errorf("got %d lines, greedy algorithm uses %d", n, greedy)
+			}
+		})
+	}
+}
+
+func TestWrapper_Hyphenation(t *testing.T) {
+	const text = "The international hyphenation algorithm breaks paragraphs nicely."
+
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		options  func(w *wrap.Wrapper)
+		expected string
+	}{
+		{
+			name:     "greedy",
+			input:    text,
+			limit:    14,
+			expected: "The interna-\ntional hyphen-\nation algo-\nrithm breaks\nparagraphs\nnicely.\n",
+		},
+		{
+			name:     "no hyphenator",
+			input:    text,
+			limit:    14,
+			options:  func(w *wrap.Wrapper) { w.Hyphenation.Hyphenator = nil },
+			expected: "The\ninternational\nhyphenation\nalgorithm\nbreaks\nparagraphs\nnicely.\n",
+		},
+		{
+			name:     "minimum raggedness",
+			input:    text,
+			limit:    14,
+			options:  func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmMinimumRaggedness },
+			expected: "The inter-\nnational hy-\nphenation al-\ngorithm\nbreaks para-\ngraphs nicely.\n",
+		},
+		{
+			name:  "minimum raggedness with penalty",
+			input: text,
+			limit: 14,
+			options: func(w *wrap.Wrapper) {
+				w.Algorithm, w.Hyphenation.Penalty = wrap.AlgorithmMinimumRaggedness, 100
+			},
+			expected: "The\ninternational\nhyphenation\nalgorithm\nbreaks para-\ngraphs nicely.\n",
+		},
+		{
+			name:     "knuth-plass",
+			input:    text,
+			limit:    20,
+			options:  func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmKnuthPlass },
+			expected: "The international\nhyphenation\nalgorithm breaks\nparagraphs nicely.\n",
+		},
+		{
+			name:     "balanced",
+			input:    text,
+			limit:    20,
+			options:  func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmBalanced },
+			expected: "The international\nhyphenation algo-\nrithm breaks para-\ngraphs nicely.\n",
+		},
+		{
+			name:     "before cutting long words",
+			input:    "Supercalifragilisticexpialidocious",
+			limit:    10,
+			options:  func(w *wrap.Wrapper) { w.CutLongWords = true },
+			expected: "Supercal-\nifragilis-\nticexpi-\nalidocious\n",
+		},
+		{
+			name:  "fragment lengths",
+			input: text,
+			limit: 14,
+			options: func(w *wrap.Wrapper) {
+				w.Hyphenation.MinLeft, w.Hyphenation.MinRight = 5, 5
+			},
+			expected: "The interna-\ntional hyphen-\nation\nalgorithm\nbreaks\nparagraphs\nnicely.\n",
+		},
+		{
+			name:     "custom hyphen",
+			input:    text,
+			limit:    14,
+			options:  func(w *wrap.Wrapper) { w.Hyphenation.Hyphen = "\u2010" },
+			expected: "The interna\u2010\ntional hyphen\u2010\nation algo\u2010\nrithm breaks\nparagraphs\nnicely.\n",
+		},
+		{
+			name:     "unicode line breaking",
+			input:    text,
+			limit:    14,
+			options:  func(w *wrap.Wrapper) { w.UnicodeLineBreaking = true },
+			expected: "The interna-\ntional hyphen-\nation algo-\nrithm breaks\nparagraphs\nnicely.\n",
+		},
+		{
+			name:     "punctuation",
+			input:    "(hyphenation)",
+			limit:    8,
+			expected: "(hyphen-\nation)\n",
+		},
+		{
+			name:     "max lines",
+			input:    text,
+			limit:    14,
+			options:  func(w *wrap.Wrapper) { w.MaxLines = 1 },
+			expected: "The interna…\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			w.Hyphenation.Hyphenator = wrap.HyphenatorEnUS()
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			if got := w.Wrap(tt.input, tt.limit); got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_FormatCharacters(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		options  func(w *wrap.Wrapper)
+		expected string
+	}{
+		{
+			name:     "soft hyphens",
+			input:    "The inter\u00adna\u00adtion\u00adal hy\u00adphen\u00adation",
+			limit:    10,
+			expected: "The inter-\nna\u00adtion\u00adal\nhy\u00adphen-\nation\n",
+		},
+		{
+			name:     "soft hyphens with minimum raggedness",
+			input:    "The inter\u00adna\u00adtion\u00adal hy\u00adphen\u00adation",
+			limit:    10,
+			options:  func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmMinimumRaggedness },
+			expected: "The inter-\nna\u00adtion-\nal hy-\nphen\u00adation\n",
+		},
+		{
+			name:     "soft hyphens with knuth-plass",
+			input:    "The inter\u00adna\u00adtion\u00adal hy\u00adphen\u00adation",
+			limit:    10,
+			options:  func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmKnuthPlass },
+			expected: "The inter-\nna\u00adtion\u00adal\nhy\u00adphen-\nation\n",
+		},
+		{
+			name:     "soft hyphens with unicode line breaking",
+			input:    "The inter\u00adna\u00adtion\u00adal hy\u00adphen\u00adation",
+			limit:    10,
+			options:  func(w *wrap.Wrapper) { w.UnicodeLineBreaking = true },
+			expected: "The inter-\nna\u00adtion\u00adal\nhy\u00adphen-\nation\n",
+		},
+		{
+			name:     "soft hyphens have no width",
+			input:    "hy\u00adphen\u00adation",
+			limit:    11,
+			expected: "hy\u00adphen\u00adation\n",
+		},
+		{
+			name:     "soft hyphens before the hyphenator",
+			input:    "hyphen\u00adation",
+			limit:    8,
+			options:  func(w *wrap.Wrapper) { w.Hyphenation.Hyphenator = wrap.HyphenatorEnUS() },
+			expected: "hyphen-\nation\n",
+		},
+		{
+			name:     "zero width spaces",
+			input:    "path/to\u200bsome/very\u200blong/file\u200bname",
+			limit:    10,
+			expected: "path/to\nsome/very\nlong/file\nname\n",
+		},
+		{
+			name:     "zero width spaces with minimum raggedness",
+			input:    "path/to\u200bsome/very\u200blong/file\u200bname",
+			limit:    10,
+			options:  func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmMinimumRaggedness },
+			expected: "path/to\nsome/very\nlong/file\nname\n",
+		},
+		{
+			name:     "zero width spaces with unicode line breaking",
+			input:    "path/to\u200bsome/very\u200blong/file\u200bname",
+			limit:    10,
+			options:  func(w *wrap.Wrapper) { w.UnicodeLineBreaking = true },
+			expected: "path/to\nsome/very\nlong/file\nname\n",
+		},
+		{
+			name:     "no-break spaces",
+			input:    "Pay 100\u00a0km and 20\u202fkg now",
+			limit:    8,
+			expected: "Pay\n100\u00a0km\nand\n20\u202fkg\nnow\n",
+		},
+		{
+			name:     "no-break spaces as breakpoints",
+			input:    "Pay 100\u00a0km and 20\u202fkg now",
+			limit:    8,
+			options:  func(w *wrap.Wrapper) { w.Breakpoints = " \u00a0\u202f" },
+			expected: "Pay\n100\u00a0km\nand\n20\u202fkg\nnow\n",
+		},
+		{
+			name:     "no-break spaces with minimum raggedness",
+			input:    "Pay 100\u00a0km and 20\u202fkg now",
+			limit:    8,
+			options:  func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmMinimumRaggedness },
+			expected: "Pay\n100\u00a0km\nand\n20\u202fkg\nnow\n",
+		},
+		{
+			name:     "word joiners",
+			input:    "well-\u2060known e-\u2060mail",
+			limit:    6,
+			expected: "well-\u2060known\ne-\u2060mail\n",
+		},
+		{
+			name:     "word joiners with minimum raggedness",
+			input:    "well-\u2060known e-\u2060mail",
+			limit:    6,
+			options:  func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmMinimumRaggedness },
+			expected: "well-\u2060known\ne-\u2060mail\n",
+		},
+		{
+			name:     "word joiners with unicode line breaking",
+			input:    "well-\u2060known e-\u2060mail",
+			limit:    6,
+			options:  func(w *wrap.Wrapper) { w.UnicodeLineBreaking = true },
+			expected: "well-\u2060known\ne-\u2060mail\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			if got := w.Wrap(tt.input, tt.limit); got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_Protect(t *testing.T) {
+	const url = "See https://example.com/a-long-path for details."
+
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		options  func(w *wrap.Wrapper)
+		expected string
+	}{
+		{
+			name:     "unprotected url",
+			input:    url,
+			limit:    20,
+			expected: "See\nhttps://example.com/a-\nlong-path for\ndetails.\n",
+		},
+		{
+			name:     "url",
+			input:    url,
+			limit:    20,
+			options:  func(w *wrap.Wrapper) { w.Protect.URLs = true },
+			expected: "See\nhttps://example.com/a-long-path\nfor details.\n",
+		},
+		{
+			name:     "url cut",
+			input:    url,
+			limit:    20,
+			options:  func(w *wrap.Wrapper) { w.Protect.URLs = true; w.CutLongWords = true },
+			expected: "See\nhttps://example.com/\na-long-path for\ndetails.\n",
+		},
+		{
+			name:     "url cut more than once",
+			input:    "https://example.com/a-long-path",
+			limit:    8,
+			options:  func(w *wrap.Wrapper) { w.Protect.URLs = true; w.CutLongWords = true },
+			expected: "https://\nexample.\ncom/a-lo\nng-path\n",
+		},
+		{
+			name:     "url with minimum raggedness",
+			input:    url,
+			limit:    20,
+			options:  func(w *wrap.Wrapper) { w.Protect.URLs = true; w.Algorithm = wrap.AlgorithmMinimumRaggedness },
+			expected: "See\nhttps://example.com/a-long-path\nfor details.\n",
+		},
+		{
+			name:  "url cut with knuth-plass",
+			input: url,
+			limit: 20,
+			options: func(w *wrap.Wrapper) {
+				w.Protect.URLs = true
+				w.Algorithm = wrap.AlgorithmKnuthPlass
+				w.CutLongWords = true
+			},
+			expected: "See\nhttps://example.com/\na-long-path for\ndetails.\n",
+		},
+		{
+			name:     "url with unicode line breaking",
+			input:    url,
+			limit:    20,
+			options:  func(w *wrap.Wrapper) { w.Protect.URLs = true; w.UnicodeLineBreaking = true },
+			expected: "See\nhttps://example.com/a-long-path\nfor details.\n",
+		},
+		{
+			name:     "url trailing punctuation",
+			input:    "(see www.example.com/a-b), then",
+			limit:    8,
+			options:  func(w *wrap.Wrapper) { w.Protect.URLs = true },
+			expected: "(see\nwww.example.com/a-b),\nthen\n",
+		},
+		{
+			name:     "url with hyphenation",
+			input:    "visit https://example.com/hyphenation",
+			limit:    20,
+			options:  func(w *wrap.Wrapper) { w.Protect.URLs = true; w.Hyphenation.Hyphenator = wrap.HyphenatorEnUS() },
+			expected: "visit\nhttps://example.com/hyphenation\n",
+		},
+		{
+			name:     "email",
+			input:    "Mail first-last@example.com today.",
+			limit:    12,
+			options:  func(w *wrap.Wrapper) { w.Protect.Emails = true },
+			expected: "Mail\nfirst-last@example.com\ntoday.\n",
+		},
+		{
+			name:     "paths",
+			input:    "Edit ./cmd/wrap-tool/main.go or C:\\Program-Files",
+			limit:    12,
+			options:  func(w *wrap.Wrapper) { w.Protect.Paths = true },
+			expected: "Edit\n./cmd/wrap-tool/main.go\nor\nC:\\Program-Files\n",
+		},
+		{
+			name:     "relative path",
+			input:    "see cmd/wrap-tool/main.go",
+			limit:    12,
+			options:  func(w *wrap.Wrapper) { w.Protect.Paths = true },
+			expected: "see\ncmd/wrap-tool/main.go\n",
+		},
+		{
+			name:     "code",
+			input:    "Run `go test -run TestWrap` now",
+			limit:    12,
+			options:  func(w *wrap.Wrapper) { w.Protect.Code = true },
+			expected: "Run\n`go test -run TestWrap`\nnow\n",
+		},
+		{
+			name:     "unclosed code",
+			input:    "Run `go test -run",
+			limit:    12,
+			options:  func(w *wrap.Wrapper) { w.Protect.Code = true },
+			expected: "Run `go test\nrun\n",
+		},
+		{
+			name:  "pattern",
+			input: "Fixed in JIRA-1234-5 and JIRA-99-1.",
+			limit: 15,
+			options: func(w *wrap.Wrapper) {
+				w.Protect.Pattern = regexp.MustCompile(`JIRA-\d+-\d+`)
+			},
+			expected: "Fixed in\nJIRA-1234-5 and\nJIRA-99-1.\n",
+		},
+		{
+			name:  "longest pattern",
+			input: "a b-c d-e f",
+			limit: 4,
+			options: func(w *wrap.Wrapper) {
+				w.Protect.Pattern = regexp.MustCompile(`b-c|b-c d-e`)
+			},
+			expected: "a\nb-c d-e\nf\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			if got := w.Wrap(tt.input, tt.limit); got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_Widows(t *testing.T) {
+	const times = "It was the best of times, it was the worst of times."
+	const hamlet = "To be, or not to be, that is the question."
+
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		options  func(w *wrap.Wrapper)
+		expected string
+	}{
+		{
+			name:     "no penalties",
+			input:    times,
+			limit:    12,
+			expected: "It was the\nbest of\ntimes, it\nwas the\nworst of\ntimes.\n",
+		},
+		{
+			name:     "orphan",
+			input:    times,
+			limit:    12,
+			options:  func(w *wrap.Wrapper) { w.Widows.OrphanPenalty = 100 },
+			expected: "It was\nthe best\nof times,\nit was\nthe worst\nof times.\n",
+		},
+		{
+			name:  "short last line",
+			input: hamlet,
+			limit: 13,
+			options: func(w *wrap.Wrapper) {
+				w.Widows.MinWidth = 10
+				w.Widows.Penalty = 100
+			},
+			expected: "To be, or\nnot to be,\nthat is\nthe question.\n",
+		},
+		{
+			name:  "long enough last line",
+			input: hamlet,
+			limit: 13,
+			options: func(w *wrap.Wrapper) {
+				w.Widows.MinWidth = 9
+				w.Widows.Penalty = 100
+			},
+			expected: "To be, or\nnot to be,\nthat is the\nquestion.\n",
+		},
+		{
+			name:     "lines that would overflow don't hide better breaks",
+			input:    "aaa bb cc dd eeee ffff",
+			limit:    7,
+			options:  func(w *wrap.Wrapper) { w.Widows.MinWidth, w.Widows.Penalty = 1, 1 },
+			expected: "aaa bb\ncc dd\neeee\nffff\n",
+		},
+		{
+			name:     "lines after an overflowing word still fit",
+			input:    "aaaaaaaaa bb ccc",
+			limit:    5,
+			options:  func(w *wrap.Wrapper) { w.Widows.MinWidth, w.Widows.Penalty = 1, 1 },
+			expected: "aaaaaaaaa\nbb\nccc\n",
+		},
+		{
+			name:     "penalty too small",
+			input:    hamlet,
+			limit:    13,
+			options:  func(w *wrap.Wrapper) { w.Widows.OrphanPenalty = 1 },
+			expected: "To be, or\nnot to be,\nthat is the\nquestion.\n",
+		},
+		{
+			name:     "single line",
+			input:    "Short",
+			limit:    20,
+			options:  func(w *wrap.Wrapper) { w.Widows = wrap.WidowOptions{MinWidth: 10, Penalty: 100, OrphanPenalty: 100} },
+			expected: "Short\n",
+		},
+		{
+			name:     "balanced",
+			input:    "Getting started with the wrap package",
+			limit:    14,
+			options:  func(w *wrap.Wrapper) { w.Algorithm, w.Widows.OrphanPenalty = wrap.AlgorithmBalanced, 100 },
+			expected: "Getting\nstarted\nwith the\nwrap package\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			w.Algorithm = wrap.AlgorithmMinimumRaggedness
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			if got := w.Wrap(tt.input, tt.limit); got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_Tabs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		options  func(w *wrap.Wrapper)
+		expected string
+	}{
+		{
+			name:     "no tab stops",
+			input:    "a\tb c d e",
+			limit:    5,
+			expected: "a\tb c\nd e\n",
+		},
+		{
+			name:     "width",
+			input:    "\tThe quick brown fox",
+			limit:    20,
+			options:  func(w *wrap.Wrapper) { w.Tabs.Width = 8 },
+			expected: "        The quick\nbrown fox\n",
+		},
+		{
+			name:     "output line prefix",
+			input:    "\tThe quick brown fox",
+			limit:    20,
+			options:  func(w *wrap.Wrapper) { w.Tabs.Width = 8; w.OutputLinePrefix = "// " },
+			expected: "//      The quick\n// brown fox\n",
+		},
+		{
+			name:  "continuation prefix",
+			input: "key\tvalue and more text",
+			limit: 16,
+			options: func(w *wrap.Wrapper) {
+				w.Tabs.Width = 8
+				w.ContinuationPrefix = "\t"
+				w.LimitIncludesPrefixSuffix = true
+			},
+			expected: "key     value\n        and more\n        text\n",
+		},
+		{
+			name:     "stops",
+			input:    "a\tb\tc d",
+			limit:    20,
+			options:  func(w *wrap.Wrapper) { w.Tabs.Stops = wrap.NewTabStops(4, 10) },
+			expected: "a   b     c d\n",
+		},
+		{
+			name:     "width after stops",
+			input:    "a\tb\tc\td",
+			limit:    20,
+			options:  func(w *wrap.Wrapper) { w.Tabs.Stops = wrap.NewTabStops(2); w.Tabs.Width = 4 },
+			expected: "a b c   d\n",
+		},
+		{
+			name:     "keep",
+			input:    "\tThe quick brown fox",
+			limit:    20,
+			options:  func(w *wrap.Wrapper) { w.Tabs.Width = 8; w.Tabs.Keep = true },
+			expected: "\tThe quick\nbrown fox\n",
+		},
+		{
+			name:     "wrapped",
+			input:    "ab\tc d\te f",
+			limit:    8,
+			options:  func(w *wrap.Wrapper) { w.Tabs.Width = 4 },
+			expected: "ab  c\nd   e f\n",
+		},
+		{
+			name:  "minimum raggedness",
+			input: "ab\tc d\te f",
+			limit: 8,
+			options: func(w *wrap.Wrapper) {
+				w.Tabs.Width = 4
+				w.Algorithm = wrap.AlgorithmMinimumRaggedness
+			},
+			expected: "ab  c\nd   e f\n",
+		},
+		{
+			name:  "hyphenation",
+			input: "\thyphenation",
+			limit: 10,
+			options: func(w *wrap.Wrapper) {
+				w.Tabs.Width = 4
+				w.Hyphenation.Hyphenator = wrap.HyphenatorEnUS()
+			},
+			expected: "    hy-\nphenation\n",
+		},
+		{
+			name:     "cut long words",
+			input:    "\tabcdefghij",
+			limit:    8,
+			options:  func(w *wrap.Wrapper) { w.Tabs.Width = 4; w.CutLongWords = true },
+			expected: "    abcd\nefghij\n",
+		},
+		{
+			name:     "max lines",
+			input:    "a\tb c d e f",
+			limit:    8,
+			options:  func(w *wrap.Wrapper) { w.Tabs.Width = 4; w.MaxLines = 1 },
+			expected: "a   b c…\n",
+		},
+		{
+			name:     "justify",
+			input:    "a\tb c d e f",
+			limit:    8,
+			options:  func(w *wrap.Wrapper) { w.Tabs.Width = 4; w.Alignment = wrap.AlignJustify },
+			expected: "a    b c\nd e f\n",
+		},
+		{
+			name:     "display width",
+			input:    "世\tx",
+			limit:    8,
+			options:  func(w *wrap.Wrapper) { w.Tabs.Width = 4; w.DisplayWidth = true },
+			expected: "世  x\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			if got := w.Wrap(tt.input, tt.limit); got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// proportional measures "i", "l" and "." as 1 unit wide, "m" and "w" as 3,
+// and everything else as 2, like a proportional font.
+var proportional = wrap.MeasurerFunc(func(s string) int {
+	switch s {
+	case "i", "l", ".":
+		return 1
+	case "m", "w":
+		return 3
+	}
+	return 2
+})
+
+func TestWrapper_Measurer(t *testing.T) {
+	const text = "ill will mmm www ill"
+
+	tests := []struct {
+		name     string
+		input    string
+		limit    int
+		options  func(w *wrap.Wrapper)
+		expected string
+	}{
+		{
+			name:     "runes",
+			input:    text,
+			limit:    12,
+			options:  func(w *wrap.Wrapper) { w.Measurer = nil },
+			expected: "ill will mmm\nwww ill\n",
+		},
+		{
+			name:     "greedy",
+			input:    text,
+			limit:    12,
+			expected: "ill will\nmmm\nwww\nill\n",
+		},
+		{
+			name:     "minimum raggedness",
+			input:    text,
+			limit:    12,
+			options:  func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmMinimumRaggedness },
+			expected: "ill will\nmmm\nwww\nill\n",
+		},
+		{
+			name:     "knuth-plass",
+			input:    text,
+			limit:    12,
+			options:  func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmKnuthPlass },
+			expected: "ill will\nmmm\nwww\nill\n",
+		},
+		{
+			name:     "cut long words",
+			input:    "mmmmmm",
+			limit:    7,
+			options:  func(w *wrap.Wrapper) { w.CutLongWords = true },
+			expected: "mm\nmm\nmm\n",
+		},
+		{
+			name:     "soft hyphens aren't measured",
+			input:    "il\u00adli\u00adli",
+			limit:    5,
+			expected: "il-\nli\u00adli\n",
+		},
+		{
+			name:     "justify with whole spaces",
+			input:    "a b c d e f",
+			limit:    13,
+			options:  func(w *wrap.Wrapper) { w.Alignment = wrap.AlignJustify },
+			expected: "a  b c\nd e f\n",
+		},
+		{
+			name:     "align with whole spaces",
+			input:    "ab cd",
+			limit:    15,
+			options:  func(w *wrap.Wrapper) { w.Alignment = wrap.AlignRight; w.OutputLineSuffix = "|" },
+			expected: " ab cd|\n",
+		},
+		{
+			name:     "max lines",
+			input:    text,
+			limit:    10,
+			options:  func(w *wrap.Wrapper) { w.MaxLines = 1; w.Ellipsis = "..." },
+			expected: "ill...\n",
+		},
+		{
+			name:     "preserve indent",
+			input:    "- ill will mmm",
+			limit:    14,
+			options:  func(w *wrap.Wrapper) { w.PreserveIndent = true },
+			expected: "- ill\n  will\n  mmm\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			w.Measurer = proportional
+			if tt.options != nil {
+				tt.options(&w)
+			}
+
+			if got := w.Wrap(tt.input, tt.limit); got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapper_Comparable(t *testing.T) {
+	a := wrap.NewWrapper()
+	a.Protect.Pattern = regexp.MustCompile(`v\d+`)
+	a.Tabs.Stops = wrap.NewTabStops(4, 10)
+	a.Measurer = proportional
+	b := a
+	if a != b {
+		t.Error("copies of a Wrapper aren't equal")
+	}
+	b.Tabs.Stops = wrap.NewTabStops(4, 10)
+	if a == b {
+		t.Error("Wrappers with different TabStops are equal")
+	}
+	b = a
+	b.Measurer = wrap.MeasurerFunc(func(s string) int { return len(s) })
+	if a == b {
+		t.Error("Wrappers with different Measurers are equal")
+	}
+}
@@ -0,0 +1,237 @@
+package wrap
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrWriterClosed is returned when writing to a writer that has been closed.
+var ErrWriterClosed = errors.New("wrap: write to closed writer")
+
+// writer wraps the text written to it, and writes the wrapped lines to an
+// underlying io.Writer as soon as they're decided.
+type writer struct {
+	w     wrapping
+	dst   io.Writer
+	limit int
+
+	// buf holds input that hasn't been wrapped yet, starting with part of
+	// the current input line.
+	buf []byte
+	// midLine is set when lines have already been written from the current
+	// input line, so buf no longer starts at the beginning of it.
+	midLine bool
+	// line is the wrapping used for the rest of the current input line once
+	// midLine is set, including any indent found by PreserveIndent.
+	line wrapping
+
+	// count is the number of lines wrapped so far, and truncated is set once
+	// MaxLines is reached and any further input is dropped.
+	count     int
+	truncated bool
+
+	st     styleState
+	out    strings.Builder
+	err    error
+	closed bool
+}
+
+// NewWriter returns an io.WriteCloser that wraps text at the given length,
+// using the settings of w, and writes the result to dst.
+//
+// Text may be written in arbitrary chunks, including ones that split runes,
+// newlines or escape sequences. Close must be called to flush the final line.
+// The output written to dst is identical to calling Wrap on the concatenated
+// input.
+func (w Wrapper) NewWriter(dst io.Writer, limit int) io.WriteCloser {
+	ww := newWrapping(w)
+	limit = ww.setup(limit)
+	return &writer{w: ww, dst: dst, limit: limit}
+}
+
+// Write wraps p, writing any lines that are complete to the underlying writer.
+func (wr *writer) Write(p []byte) (int, error) {
+	if wr.closed {
+		return 0, ErrWriterClosed
+	}
+	if wr.err != nil {
+		return 0, wr.err
+	}
+	if wr.truncated {
+		return len(p), nil
+	}
+
+	wr.buf = append(wr.buf, p...)
+
+	newline := []byte(wr.w.Newline)
+	consumed := 0
+	for !wr.truncated {
+		idx := bytes.Index(wr.buf[consumed:], newline)
+		if idx < 0 {
+			break
+		}
+		wr.wrapLine(string(wr.buf[consumed:consumed+idx]), true)
+		if !wr.truncated {
+			wr.out.WriteString(wr.w.Newline)
+		}
+		consumed += idx + len(newline)
+	}
+	if wr.truncated {
+		wr.buf = wr.buf[:0]
+	} else {
+		wr.buf = append(wr.buf[:0], wr.buf[consumed:]...)
+		wr.writeDecided()
+	}
+
+	if err := wr.flush(); err != nil {
+		return len(p), err
+	}
+	return len(p), nil
+}
+
+// Close wraps and writes any remaining text as the final line. It doesn't
+// close the underlying writer.
+func (wr *writer) Close() error {
+	if wr.closed {
+		return wr.err
+	}
+	wr.closed = true
+	if wr.err != nil {
+		return wr.err
+	}
+
+	if !wr.truncated {
+		wr.wrapLine(string(wr.buf), false)
+	}
+	wr.buf = nil
+	if !wr.w.StripTrailingNewline {
+		wr.out.WriteString(wr.w.Newline)
+	}
+	return wr.flush()
+}
+
+// wrapLine wraps the rest of the current input line. more is set if there's
+// another input line after it.
+func (wr *writer) wrapLine(s string, more bool) {
+	lw := wr.line
+	if !wr.midLine {
+		s = strings.TrimPrefix(s, wr.w.TrimInputPrefix)
+		s = strings.TrimSuffix(s, wr.w.TrimInputSuffix)
+		lw = wr.w
+		if wr.w.PreserveIndent {
+			lw, s = wr.w.withIndent(s)
+		}
+	} else {
+		s = strings.TrimSuffix(s, wr.w.TrimInputSuffix)
+	}
+	// The writer doesn't report where lines came from, so offsets are unused
+	lines := lw.lineBuilder(nil, s, 0, wr.limit, !wr.midLine)
+	lines, wr.truncated = lw.limitLines(lines, wr.count, wr.limit, !wr.midLine, more)
+	wr.count += len(lines)
+	lw.render(lines, wr.limit, !wr.midLine, &wr.st)
+	wr.w.writeLines(&wr.out, lines)
+	wr.midLine = false
+}
+
+// writeDecided writes the lines at the start of the buffered partial input
+// line that can't be affected by any further input. Only the greedy algorithm
+// decides lines without seeing the whole input line, and only if no spans are
+// protected, as text further on can extend a span back over an earlier break.
+func (wr *writer) writeDecided() {
+	if wr.w.Algorithm != AlgorithmGreedy || wr.limit < 1 || wr.w.Protect.enabled() {
+		return
+	}
+
+	// Hold back anything that may turn out to be part of the input suffix or
+	// a newline, or that ends part way through a rune.
+	hold := len(wr.w.TrimInputSuffix) + len(wr.w.Newline) - 1
+	end := runeBoundary(wr.buf, len(wr.buf)-hold)
+
+	start := 0
+	lw := wr.line
+	if !wr.midLine {
+		// The input prefix can only be trimmed once it's been seen in full
+		safe := string(wr.buf[:end])
+		if len(safe) < len(wr.w.TrimInputPrefix) && strings.HasPrefix(wr.w.TrimInputPrefix, safe) {
+			return
+		}
+		if strings.HasPrefix(safe, wr.w.TrimInputPrefix) {
+			start = len(wr.w.TrimInputPrefix)
+		}
+
+		lw = wr.w
+		if wr.w.PreserveIndent {
+			// The indent can only be found once the text after it is seen
+			s := safe[start:]
+			if !wr.w.indentDecided(s) {
+				return
+			}
+			var rest string
+			lw, rest = wr.w.withIndent(s)
+			start += len(s) - len(rest)
+		}
+	}
+
+	// The last line allowed by MaxLines depends on whether anything follows it
+	for start < end && (wr.w.MaxLines < 1 || wr.count < wr.w.MaxLines-1) {
+		s := string(wr.buf[start:end])
+		trimmed := lw.trimBreakpoints(s)
+		offset := len(s) - len(trimmed)
+
+		// The break is only decided once text beyond everything examined
+		// to find it has been seen, as the clusters at the end of the buffer
+		// may still grow.
+		first := !wr.midLine
+		lw.startLine(first)
+		lineEnd, next, seen, reason := lw.greedyBreak(trimmed, lw.lineLimit(wr.limit, first))
+		if next < 0 || seen >= len(trimmed) {
+			break
+		}
+
+		lines := []Line{lw.brokenLine(trimTrailingSpaces(trimmed[:lineEnd]), 0, reason)}
+		lw.render(lines, wr.limit, first, &wr.st)
+		wr.w.writeLines(&wr.out, lines)
+		wr.out.WriteString(wr.w.Newline)
+		wr.count++
+		wr.midLine = true
+		start += offset + next
+	}
+
+	if wr.midLine {
+		wr.line = lw
+		wr.buf = append(wr.buf[:0], wr.buf[start:]...)
+	}
+}
+
+// flush writes any wrapped output to the underlying writer.
+func (wr *writer) flush() error {
+	if wr.out.Len() == 0 {
+		return nil
+	}
+	_, err := io.WriteString(wr.dst, wr.out.String())
+	wr.out.Reset()
+	if err != nil {
+		wr.err = err
+	}
+	return err
+}
+
+// runeBoundary returns the largest index no greater than end at which p can
+// be split without cutting a rune in half.
+func runeBoundary(p []byte, end int) int {
+	if end <= 0 {
+		return 0
+	}
+	// Find the start of the last rune before end
+	i := end - 1
+	for i > 0 && end-i < utf8.UTFMax && !utf8.RuneStart(p[i]) {
+		i--
+	}
+	if utf8.FullRune(p[i:end]) {
+		return end
+	}
+	return i
+}
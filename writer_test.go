@@ -0,0 +1,167 @@
+package wrap_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bbrks/wrap/v2"
+)
+
+// writeChunks writes s to a new writer in chunks of the given size, and
+// returns everything written to the destination.
+func writeChunks(t *testing.T, w wrap.Wrapper, s string, limit, size int) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	wr := w.NewWriter(&buf, limit)
+	for len(s) > 0 {
+		n := size
+		if n > len(s) {
+			n = len(s)
+		}
+		if _, err := wr.Write([]byte(s[:n])); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		s = s[n:]
+	}
+	if err := wr.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return buf.String()
+}
+
+func TestWrapper_NewWriter(t *testing.T) {
+	options := map[string]func(w *wrap.Wrapper){
+		"default":              func(w *wrap.Wrapper) {},
+		"strip":                func(w *wrap.Wrapper) { w.StripTrailingNewline = true },
+		"cut":                  func(w *wrap.Wrapper) { w.CutLongWords = true },
+		"minimum raggedness":   func(w *wrap.Wrapper) { w.MinimumRaggedness = true },
+		"knuth-plass":          func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmKnuthPlass },
+		"balanced":             func(w *wrap.Wrapper) { w.Algorithm = wrap.AlgorithmBalanced },
+		"display width":        func(w *wrap.Wrapper) { w.DisplayWidth = true },
+		"CRLF":                 func(w *wrap.Wrapper) { w.Newline = "\r\n" },
+		"prefix and suffix":    func(w *wrap.Wrapper) { w.OutputLinePrefix, w.OutputLineSuffix = "// ", " |" },
+		"trim input":           func(w *wrap.Wrapper) { w.TrimInputPrefix, w.TrimInputSuffix = "Lorem ", "." },
+		"reapply styles":       func(w *wrap.Wrapper) { w.ReapplyStyles = true },
+		"escapes and cut":      func(w *wrap.Wrapper) { w.EscapeSequences, w.CutLongWords = true, true },
+		"multibyte breakpoint": func(w *wrap.Wrapper) { w.Breakpoints = " £" },
+		"first line and continuation prefix": func(w *wrap.Wrapper) {
+			w.FirstLinePrefix, w.ContinuationPrefix = "- ", "  "
+		},
+		"preserve indent": func(w *wrap.Wrapper) {
+			w.PreserveIndent, w.TrimInputPrefix, w.ContinuationPrefix = true, "Lorem ", "  "
+		},
+		"center": func(w *wrap.Wrapper) {
+			w.Alignment, w.Fill, w.OutputLineSuffix = wrap.AlignCenter, '·', "|"
+		},
+		"justify": func(w *wrap.Wrapper) {
+			w.Alignment, w.OutputLineSuffix, w.UnicodeLineBreaking = wrap.AlignJustify, " |", true
+		},
+		"unicode line breaking": func(w *wrap.Wrapper) {
+			w.UnicodeLineBreaking, w.DisplayWidth, w.EscapeSequences = true, true, true
+		},
+		"unicode line breaking and cut": func(w *wrap.Wrapper) { w.UnicodeLineBreaking, w.CutLongWords = true, true },
+		"max lines":                     func(w *wrap.Wrapper) { w.MaxLines = 3 },
+		"hyphenation":                   func(w *wrap.Wrapper) { w.Hyphenation.Hyphenator = wrap.HyphenatorEnUS() },
+		"protect": func(w *wrap.Wrapper) {
+			w.Protect = wrap.ProtectOptions{URLs: true, Emails: true, Paths: true, Code: true}
+		},
+		"tabs": func(w *wrap.Wrapper) {
+			w.Tabs, w.OutputLinePrefix, w.ContinuationPrefix = wrap.TabOptions{Width: 4, Stops: wrap.NewTabStops(6)}, "#", "\t"
+		},
+		"kept tabs and hyphenation": func(w *wrap.Wrapper) {
+			w.Tabs, w.Hyphenation.Hyphenator = wrap.TabOptions{Width: 8, Keep: true}, wrap.HyphenatorEnUS()
+		},
+		"measurer": func(w *wrap.Wrapper) {
+			w.Measurer, w.Alignment, w.Fill = proportional, wrap.AlignCenter, '·'
+		},
+		"max lines and prefix": func(w *wrap.Wrapper) {
+			w.MaxLines, w.FirstLinePrefix, w.Ellipsis = 1, "> ", "..."
+		},
+	}
+
+	inputs := append([]string{
+		"\x1b[31mcolored text that wraps\x1b[0m across several lines\n\x1b[1mbold\x1b[0m",
+		"🇺🇸🇬🇧🇯🇵 café 日本語テスト 👨‍👩‍👧‍👦",
+		"line one\r\nline two\r\n",
+		"日本語のテキストです。 pages 10-20, $(12.50)\u2028well-known \x1b[1mfacts\x1b[0m",
+		"- The quick brown fox jumps over the lazy dog.\n  12. nested item that wraps\n> > quote\n  \t-\n",
+		"inter\u00adna\u00adtion\u00adal path/to\u200bfile 10\u00a0km well-\u2060known",
+		"See https://example.com/a-b, first-last@example.com or `go test -run X` in ./a-b/c.go",
+		"\tname\tvalue that wraps\n\t\thyphenation\ta\tb\tc\td\te\tf",
+	}, loremIpsums...)
+
+	for name, option := range options {
+		t.Run(name, func(t *testing.T) {
+			w := wrap.NewWrapper()
+			option(&w)
+			for _, limit := range testLimits {
+				for _, s := range inputs {
+					expected := w.Wrap(s, limit)
+					for _, size := range []int{1, 2, 3, 7, 64, len(s) + 1} {
+						if got := writeChunks(t, w, s, limit, size); got != expected {
+							t.Fatalf("limit %d, chunk size %d: got %q, want %q", limit, size, got, expected)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWrapper_NewWriter_WritesDecidedLines(t *testing.T) {
+	var buf bytes.Buffer
+	wr := wrap.NewWrapper().NewWriter(&buf, 10)
+
+	// The first line is decided as soon as text beyond the limit is seen,
+	// even though the input line hasn't ended.
+	if _, err := wr.Write([]byte("hello world, this is")); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "hello\nworld,\n" {
+		t.Errorf("got %q before close", got)
+	}
+
+	if err := wr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "hello\nworld,\nthis is\n" {
+		t.Errorf("got %q after close", got)
+	}
+}
+
+func TestWrapper_NewWriter_Closed(t *testing.T) {
+	var buf bytes.Buffer
+	wr := wrap.NewWrapper().NewWriter(&buf, 10)
+	if err := wr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wr.Write([]byte("hello")); !errors.Is(err, wrap.ErrWriterClosed) {
+		t.Errorf("got error %v, want %v", err, wrap.ErrWriterClosed)
+	}
+	if got := buf.String(); got != "\n" {
+		t.Errorf("got %q, want %q", got, "\n")
+	}
+}
+
+// errWriter fails every write.
+type errWriter struct{}
+
+var errWrite = errors.New("write failed")
+
+func (errWriter) Write(p []byte) (int, error) { return 0, errWrite }
+
+func TestWrapper_NewWriter_Error(t *testing.T) {
+	wr := wrap.NewWrapper().NewWriter(errWriter{}, 5)
+	if _, err := wr.Write([]byte(strings.Repeat("hello world\n", 2))); !errors.Is(err, errWrite) {
+		t.Errorf("got error %v, want %v", err, errWrite)
+	}
+	if _, err := wr.Write([]byte("more")); !errors.Is(err, errWrite) {
+		t.Errorf("got error %v, want %v", err, errWrite)
+	}
+	if err := wr.Close(); !errors.Is(err, errWrite) {
+		t.Errorf("got error %v, want %v", err, errWrite)
+	}
+}